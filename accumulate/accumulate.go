@@ -1,35 +1,89 @@
 package accumulate
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/ybbus/jsonrpc/v3"
+	"golang.org/x/time/rate"
 )
 
+const APIv2 = "v2"
+const APIv3 = "v3"
+
+// Client queries accounts, data entries and chains of the Accumulate network.
+// It is implemented by AccumulateClient for v2 API and by AccumulateV3Client for v3 API.
+type Client interface {
+	QueryToken(ctx context.Context, token *Params) (*QueryTokenResponse, error)
+	QueryTokenAccount(ctx context.Context, account *Params) (*QueryTokenAccountResponse, error)
+	QueryLatestDataEntry(ctx context.Context, dataAccount *Params) (*QueryDataResponse, error)
+	QueryDataSet(ctx context.Context, dataAccount *Params) (*QueryDataSetResponse, error)
+	QueryTxHistory(ctx context.Context, account *Params) (*QueryTxHistoryResponse, error)
+
+	StartHealthChecks(ctx context.Context, interval time.Duration)
+	EndpointsStatus() []*EndpointStatus
+	ServedCalls() map[string]int64
+}
+
+// AccumulateClient is the Accumulate v2 API client
 type AccumulateClient struct {
-	API      string
-	Client   jsonrpc.RPCClient
-	Validate *validator.Validate
+	// Endpoints of Accumulate nodes, calls are routed to the healthiest one
+	Endpoints []*Endpoint
+	Validate  *validator.Validate
+	// Limiter limits outbound requests, nil means unlimited
+	Limiter *rate.Limiter
+	// Retry policy for retryable errors, nil disables retries
+	Retry *RetryPolicy
 }
 
-// NewAccumulateClient constructs the Accumulate client
-func NewAccumulateClient(apiURL string, timeout time.Duration) *AccumulateClient {
+// NewAccumulateClient constructs the Accumulate client for one or more node endpoints
+func NewAccumulateClient(apiURLs []string, timeout time.Duration) *AccumulateClient {
 
-	c := &AccumulateClient{API: apiURL}
+	c := &AccumulateClient{}
 
 	// init validator
 	c.Validate = validator.New()
 
-	// set 5 seconds timeout
+	// set client timeout and keep connections of concurrent requests alive
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 64
+
 	opts := &jsonrpc.RPCClientOpts{}
 	opts.HTTPClient = &http.Client{
-		Timeout: timeout * time.Second,
+		Timeout:   timeout,
+		Transport: transport,
 	}
 
-	c.Client = jsonrpc.NewClientWithOpts(apiURL, opts)
+	for _, apiURL := range apiURLs {
+		c.Endpoints = append(c.Endpoints, NewEndpoint(apiURL, jsonrpc.NewClientWithOpts(apiURL, opts), "version"))
+	}
 
 	return c
 
 }
+
+// SetRateLimit limits outbound requests per second, 0 disables the limit
+func (c *AccumulateClient) SetRateLimit(rps float64) {
+
+	if rps <= 0 {
+		c.Limiter = nil
+		return
+	}
+
+	c.Limiter = rate.NewLimiter(rate.Limit(rps), 1)
+
+}
+
+// SetCircuitBreaker sets circuit breaker of every endpoint, 0 failureThreshold disables them
+func (c *AccumulateClient) SetCircuitBreaker(failureThreshold int, cooldown time.Duration) {
+
+	for _, e := range c.Endpoints {
+		e.Breaker = nil
+		if failureThreshold > 0 {
+			e.Breaker = NewCircuitBreaker(failureThreshold, cooldown)
+		}
+	}
+
+}
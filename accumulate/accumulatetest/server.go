@@ -0,0 +1,515 @@
+// Package accumulatetest provides an in-process Accumulate JSON-RPC server for tests, serving both v2 and v3 APIs.
+// Fixtures are scripted with Add* methods, failures with Fail* and Delay methods.
+package accumulatetest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+)
+
+// JSON-RPC error codes returned by Accumulate API, v3 reports missing records with its own code
+const (
+	ErrCodeInternal   = -32800
+	ErrCodeNotFound   = -32807
+	ErrCodeMethod     = -32601
+	ErrCodeV3NotFound = -33404
+)
+
+// Server is a mock Accumulate node serving v2 query, query-data, query-data-set, query-tx-history and version methods,
+// and v3 query of account, data and main chain records and node-info methods
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	accounts  map[string]interface{}
+	data      map[string][]*accumulate.DataEntry
+	txHistory map[string][]*accumulate.QueryTokenTxResponse
+	errors    map[string]*rpcError
+	failNext  map[string][]*rpcError
+	delays    map[string]time.Duration
+	calls     map[string]int
+}
+
+type rpcRequest struct {
+	ID     interface{} `json:"id"`
+	Method string      `json:"method"`
+	Params *rpcParams  `json:"params"`
+}
+
+// rpcParams holds params of both APIs, v3 queries set scope and query instead of url
+type rpcParams struct {
+	accumulate.Params
+	Scope string              `json:"scope"`
+	Query *accumulate.V3Query `json:"query"`
+}
+
+type rpcResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type multiResponse struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+	Start int64       `json:"start"`
+	Count int64       `json:"count"`
+	Total int64       `json:"total"`
+}
+
+type chainResponse struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// NewServer starts mock server without fixtures, it must be closed by the caller
+func NewServer() *Server {
+
+	s := &Server{
+		accounts:  make(map[string]interface{}),
+		data:      make(map[string][]*accumulate.DataEntry),
+		txHistory: make(map[string][]*accumulate.QueryTokenTxResponse),
+		errors:    make(map[string]*rpcError),
+		failNext:  make(map[string][]*rpcError),
+		delays:    make(map[string]time.Duration),
+		calls:     make(map[string]int),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+
+}
+
+// AddToken adds token issuer
+func (s *Server) AddToken(url, symbol string, precision int64, issued, supplyLimit string) {
+
+	s.setAccount(url, &accumulate.Token{
+		Type:        "tokenIssuer",
+		Authorities: []*accumulate.URL{{URL: url + "/book"}},
+		URL:         url,
+		Symbol:      symbol,
+		Precision:   precision,
+		Issued:      issued,
+		SupplyLimit: supplyLimit,
+	})
+
+}
+
+// AddTokenAccount adds token account with balance
+func (s *Server) AddTokenAccount(url, tokenURL, balance string) {
+
+	s.setAccount(url, &accumulate.TokenAccount{
+		Type:     "tokenAccount",
+		URL:      url,
+		TokenURL: tokenURL,
+		Balance:  balance,
+	})
+
+}
+
+// AddDataEntry appends data entry to data account, every data item is hex encoded
+func (s *Server) AddDataEntry(dataAccount string, data ...[]byte) {
+
+	entry := &accumulate.DataEntry{}
+	entry.Entry.Type = "doubleHash"
+
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+		entry.Entry.Data = append(entry.Entry.Data, hex.EncodeToString(d))
+	}
+	entry.EntryHash = hex.EncodeToString(h.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(dataAccount)
+	s.data[key] = append(s.data[key], entry)
+
+}
+
+// AddEmptyDataEntry appends data entry of a transaction without data. v2 API serves it without type and data,
+// v3 API without entry of the transaction body.
+func (s *Server) AddEmptyDataEntry(dataAccount, hash string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(dataAccount)
+	s.data[key] = append(s.data[key], &accumulate.DataEntry{EntryHash: hash})
+
+}
+
+// AddStakingEntry appends staking registry entry of record to data account
+func (s *Server) AddStakingEntry(dataAccount string, record *schema.StakingRecord) {
+
+	entry := map[string]string{
+		"type":     record.Type,
+		"identity": record.Identity,
+		"stake":    record.Stake,
+		"rewards":  record.Rewards,
+	}
+	if record.Status != "" {
+		entry["status"] = record.Status
+	}
+	if record.Delegate != "" {
+		entry["delegate"] = record.Delegate
+	}
+	if record.AcceptingDelegates != "" {
+		entry["acceptingDelegates"] = record.AcceptingDelegates
+	}
+
+	data, _ := json.Marshal(entry)
+
+	s.AddDataEntry(dataAccount, data)
+
+}
+
+// AddTx appends transaction to tx history of account
+func (s *Server) AddTx(account string, tx *accumulate.QueryTokenTxResponse) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(account)
+	s.txHistory[key] = append(s.txHistory[key], tx)
+
+}
+
+// AddSignature appends signature to the main chain of account. v2 API serves it as a transaction of signature type,
+// v3 API as a signature message without transaction.
+func (s *Server) AddSignature(account, hash string) {
+
+	s.AddTx(account, &accumulate.QueryTokenTxResponse{Type: "signature", TxHash: hash})
+
+}
+
+// FailURL makes every call for url fail with JSON-RPC error, 0 code clears the failure
+func (s *Server) FailURL(url string, code int, message string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if code == 0 {
+		delete(s.errors, strings.ToLower(url))
+		return
+	}
+
+	s.errors[strings.ToLower(url)] = &rpcError{Code: code, Message: message}
+
+}
+
+// FailNext makes the next n calls of method fail with JSON-RPC error
+func (s *Server) FailNext(method string, n int, code int, message string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.failNext[method] = append(s.failNext[method], &rpcError{Code: code, Message: message})
+	}
+
+}
+
+// Delay delays responses to calls for url, use it to simulate timeouts. 0 clears the delay.
+func (s *Server) Delay(url string, d time.Duration) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delays[strings.ToLower(url)] = d
+
+}
+
+// Calls returns number of received calls of method
+func (s *Server) Calls(method string) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[method]
+
+}
+
+func (s *Server) setAccount(url string, account interface{}) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accounts[strings.ToLower(url)] = account
+
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+
+	req := &rpcRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Params == nil {
+		req.Params = &rpcParams{}
+	}
+
+	url := strings.ToLower(req.Params.URL)
+	if req.Params.Scope != "" {
+		url = strings.ToLower(req.Params.Scope)
+	}
+
+	s.mu.Lock()
+	s.calls[req.Method]++
+	delay := s.delays[url]
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	result, rpcErr := s.dispatch(req.Method, url, req.Params)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+
+}
+
+// dispatch returns result of the call or its error
+func (s *Server) dispatch(method, url string, params *rpcParams) (interface{}, *rpcError) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if queue := s.failNext[method]; len(queue) > 0 {
+		s.failNext[method] = queue[1:]
+		return nil, queue[0]
+	}
+
+	if method == "version" {
+		return chainResponse{Type: "version", Data: map[string]string{"version": "mock"}}, nil
+	}
+	if method == "node-info" {
+		return map[string]string{"network": "mock", "version": "mock"}, nil
+	}
+
+	if err, ok := s.errors[url]; ok {
+		return nil, err
+	}
+
+	notFound := &rpcError{Code: ErrCodeNotFound, Message: "Not Found"}
+
+	if method == "query" && params.Query != nil {
+		return s.queryV3(url, params.Query)
+	}
+
+	switch method {
+	case "query":
+		account, ok := s.accounts[url]
+		if !ok {
+			return nil, notFound
+		}
+		switch a := account.(type) {
+		case *accumulate.Token:
+			return chainResponse{Type: a.Type, Data: a}, nil
+		case *accumulate.TokenAccount:
+			return chainResponse{Type: a.Type, Data: a}, nil
+		}
+	case "query-data":
+		entries := s.data[url]
+		if len(entries) == 0 {
+			return nil, notFound
+		}
+		return chainResponse{Type: "dataEntry", Data: entries[len(entries)-1]}, nil
+	case "query-data-set":
+		entries, ok := s.data[url]
+		if !ok {
+			return nil, notFound
+		}
+		page := paginate(entries, params.Start, params.Count)
+		return multiResponse{Type: "dataSet", Items: page, Start: params.Start, Count: int64(len(page)), Total: int64(len(entries))}, nil
+	case "query-tx-history":
+		history, ok := s.txHistory[url]
+		if !ok {
+			return nil, notFound
+		}
+		page := paginate(history, params.Start, params.Count)
+		return multiResponse{Type: "txHistory", Items: page, Start: params.Start, Count: int64(len(page)), Total: int64(len(history))}, nil
+	}
+
+	return nil, &rpcError{Code: ErrCodeMethod, Message: "Method not found"}
+
+}
+
+// paginate returns page of items within bounds
+func paginate[T any](items []T, start, count int64) []T {
+
+	if start > int64(len(items)) {
+		start = int64(len(items))
+	}
+
+	end := int64(len(items))
+	if count > 0 && start+count < end {
+		end = start + count
+	}
+
+	return items[start:end]
+
+}
+
+// queryV3 returns v3 record of account, its latest data entry, or a range of its data or main chain entries.
+// Records are built as the v3 API encodes them, transactions are always expanded.
+func (s *Server) queryV3(url string, query *accumulate.V3Query) (interface{}, *rpcError) {
+
+	notFound := &rpcError{Code: ErrCodeV3NotFound, Message: "Not Found"}
+
+	switch {
+	case query.QueryType == "default":
+		account, ok := s.accounts[url]
+		if !ok {
+			return nil, notFound
+		}
+		return map[string]interface{}{"recordType": "account", "account": account}, nil
+	case query.QueryType == "data" && query.Range == nil:
+		entries := s.data[url]
+		if len(entries) == 0 {
+			return nil, notFound
+		}
+		return v3DataEntry(url, int64(len(entries)-1), entries[len(entries)-1]), nil
+	case query.QueryType == "data":
+		entries, ok := s.data[url]
+		if !ok {
+			return nil, notFound
+		}
+		start, page := v3Page(entries, query.Range)
+		records := []interface{}{}
+		for i, entry := range page {
+			records = append(records, v3DataEntry(url, start+int64(i), entry))
+		}
+		return v3RecordRange(records, start, int64(len(entries))), nil
+	case query.QueryType == "chain" && query.Name == "main":
+		history, ok := s.txHistory[url]
+		if !ok {
+			return nil, notFound
+		}
+		start, page := v3Page(history, query.Range)
+		records := []interface{}{}
+		for i, tx := range page {
+			records = append(records, v3Tx(url, start+int64(i), tx))
+		}
+		return v3RecordRange(records, start, int64(len(history))), nil
+	}
+
+	return nil, &rpcError{Code: ErrCodeInternal, Message: "unsupported query " + query.QueryType}
+
+}
+
+// v3Page returns start and page of items within range
+func v3Page[T any](items []T, rng *accumulate.V3Range) (int64, []T) {
+
+	count := int64(0)
+	if rng.Count != nil {
+		count = *rng.Count
+	}
+
+	return rng.Start, paginate(items, rng.Start, count)
+
+}
+
+func v3RecordRange(records []interface{}, start, total int64) map[string]interface{} {
+
+	return map[string]interface{}{"recordType": "range", "records": records, "start": start, "total": total}
+
+}
+
+// v3DataEntry returns chain entry record of data entry with expanded writeData transaction
+func v3DataEntry(account string, index int64, entry *accumulate.DataEntry) map[string]interface{} {
+
+	body := map[string]interface{}{"type": "writeData"}
+	if entry.Entry.Type != "" {
+		body["entry"] = map[string]interface{}{"type": entry.Entry.Type, "data": entry.Entry.Data}
+	}
+
+	return v3ChainEntry(account, "data", index, entry.EntryHash, "acc://"+entry.EntryHash+"@"+strings.TrimPrefix(account, "acc://"), account, "", body)
+
+}
+
+// v3Tx returns main chain entry record of transaction, its ID is built from hash and sender
+func v3Tx(account string, index int64, tx *accumulate.QueryTokenTxResponse) map[string]interface{} {
+
+	if tx.Type == "signature" {
+		return map[string]interface{}{
+			"recordType": "chainEntry",
+			"account":    account,
+			"name":       "main",
+			"index":      index,
+			"entry":      tx.TxHash,
+			"value": map[string]interface{}{
+				"recordType": "message",
+				"id":         "acc://" + tx.TxHash + "@" + strings.TrimPrefix(account, "acc://"),
+				"message":    map[string]interface{}{"type": "signature", "signature": map[string]interface{}{"type": "ed25519"}},
+				"status":     "delivered",
+			},
+		}
+	}
+
+	body := map[string]interface{}{"type": tx.Type}
+	principal := account
+
+	if tx.Data != nil {
+		body["to"] = tx.Data.To
+		body["cause"] = tx.Data.Cause
+		body["source"] = tx.Data.Source
+		body["token"] = tx.Data.Token
+		body["amount"] = tx.Data.Amount
+		body["isRefund"] = tx.Data.IsRefund
+		if tx.Data.From != "" {
+			principal = tx.Data.From
+		}
+	}
+
+	return v3ChainEntry(account, "main", index, tx.TxHash, "acc://"+tx.TxHash+"@"+strings.TrimPrefix(principal, "acc://"), principal, tx.Transaction.Header.Memo, body)
+
+}
+
+func v3ChainEntry(account, name string, index int64, entry, id, principal, memo string, body map[string]interface{}) map[string]interface{} {
+
+	return map[string]interface{}{
+		"recordType": "chainEntry",
+		"account":    account,
+		"name":       name,
+		"index":      index,
+		"entry":      entry,
+		"value": map[string]interface{}{
+			"recordType": "message",
+			"id":         id,
+			"message": map[string]interface{}{
+				"type": "transaction",
+				"transaction": map[string]interface{}{
+					"header": map[string]interface{}{"principal": principal, "memo": memo},
+					"body":   body,
+				},
+			},
+			"status": "delivered",
+		},
+	}
+
+}
@@ -2,9 +2,7 @@ package accumulate
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/labstack/gommon/log"
 	"gitlab.com/accumulatenetwork/accumulate/protocol"
 )
 
@@ -83,27 +81,30 @@ type Params struct {
 }
 
 type QueryADIResponse struct {
-	Data *ADI `json:"data"`
+	Data *ADI `json:"data" validate:"required"`
 }
 
 type QueryKeyPageResponse struct {
-	Data *KeyPage `json:"data"`
+	Data *KeyPage `json:"data" validate:"required"`
 }
 
 type QueryTokenResponse struct {
-	Data *Token `json:"data"`
+	Data *Token `json:"data" validate:"required"`
 }
 
 type QueryTokenAccountResponse struct {
-	Data *TokenAccount `json:"data"`
+	Data *TokenAccount `json:"data" validate:"required"`
 }
 
 type QueryDataResponse struct {
-	Data *DataEntry `json:"data"`
+	Data *DataEntry `json:"data" validate:"required"`
 }
 
 type QueryDataSetResponse struct {
 	Items []*DataEntry `json:"items"`
+	Start int64        `json:"start"`
+	Count int64        `json:"count"`
+	Total int64        `json:"total"`
 }
 
 type QueryPendingChainResponse struct {
@@ -124,260 +125,52 @@ type QueryTokenTxResponse struct {
 
 type QueryTxHistoryResponse struct {
 	Items []*QueryTokenTxResponse `json:"items"`
+	Start int64                   `json:"start"`
+	Count int64                   `json:"count"`
+	Total int64                   `json:"total"`
 }
 
 // QueryADI gets ADI info
-func (c *AccumulateClient) QueryADI(adi *Params) (*QueryADIResponse, error) {
-
-	adiResp := &QueryADIResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query", &adi)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(adiResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(adiResp)
-	if err != nil {
-		return nil, err
-	}
-
-	return adiResp, nil
-
+func (c *AccumulateClient) QueryADI(ctx context.Context, adi *Params) (*QueryADIResponse, error) {
+	return query[QueryADIResponse](ctx, c, "query", adi.URL, adi)
 }
 
 // QueryKeyPage gets Key page info
-func (c *AccumulateClient) QueryKeyPage(page *Params) (*QueryKeyPageResponse, error) {
-
-	pageResp := &QueryKeyPageResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query", &page)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(pageResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(pageResp)
-	if err != nil {
-		return nil, err
-	}
-
-	return pageResp, nil
-
+func (c *AccumulateClient) QueryKeyPage(ctx context.Context, page *Params) (*QueryKeyPageResponse, error) {
+	return query[QueryKeyPageResponse](ctx, c, "query", page.URL, page)
 }
 
 // QueryToken gets Token info
-func (c *AccumulateClient) QueryToken(token *Params) (*QueryTokenResponse, error) {
-
-	tokenResp := &QueryTokenResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query", &token)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(tokenResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(tokenResp)
-	if err != nil {
-		return nil, err
-	}
-
-	return tokenResp, nil
-
+func (c *AccumulateClient) QueryToken(ctx context.Context, token *Params) (*QueryTokenResponse, error) {
+	return query[QueryTokenResponse](ctx, c, "query", token.URL, token)
 }
 
 // QueryTokenAccount gets Token Account info
-func (c *AccumulateClient) QueryTokenAccount(account *Params) (*QueryTokenAccountResponse, error) {
-
-	accountResp := &QueryTokenAccountResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query", &account)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(accountResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(accountResp)
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
-	}
-
-	return accountResp, nil
-
+func (c *AccumulateClient) QueryTokenAccount(ctx context.Context, account *Params) (*QueryTokenAccountResponse, error) {
+	return query[QueryTokenAccountResponse](ctx, c, "query", account.URL, account)
 }
 
 // QueryTokenTx gets token tx by url
-func (c *AccumulateClient) QueryTokenTx(tx *Params) (*QueryTokenTxResponse, error) {
-
-	txResp := &QueryTokenTxResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query", &tx)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(txResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(txResp)
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
-	}
-
-	return txResp, nil
-
+func (c *AccumulateClient) QueryTokenTx(ctx context.Context, tx *Params) (*QueryTokenTxResponse, error) {
+	return query[QueryTokenTxResponse](ctx, c, "query", tx.URL, tx)
 }
 
 // QueryTxHistory gets tx history of account
-func (c *AccumulateClient) QueryTxHistory(account *Params) (*QueryTxHistoryResponse, error) {
-
-	historyResp := &QueryTxHistoryResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query-tx-history", &account)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(historyResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(historyResp)
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
-	}
-
-	return historyResp, nil
-
+func (c *AccumulateClient) QueryTxHistory(ctx context.Context, account *Params) (*QueryTxHistoryResponse, error) {
+	return query[QueryTxHistoryResponse](ctx, c, "query-tx-history", account.URL, account)
 }
 
 // QueryLatestDataEntry gets latest data entry from data account
-func (c *AccumulateClient) QueryLatestDataEntry(dataAccount *Params) (*QueryDataResponse, error) {
-
-	dataResp := &QueryDataResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query-data", &dataAccount)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(dataResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(dataResp)
-	if err != nil {
-		return nil, err
-	}
-
-	return dataResp, nil
-
+func (c *AccumulateClient) QueryLatestDataEntry(ctx context.Context, dataAccount *Params) (*QueryDataResponse, error) {
+	return query[QueryDataResponse](ctx, c, "query-data", dataAccount.URL, dataAccount)
 }
 
-// QueryLatestDataEntry gets latest data entry from data account
-func (c *AccumulateClient) QueryDataEntry(dataAccount *Params) (*QueryDataResponse, error) {
-
-	dataResp := &QueryDataResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query", &dataAccount)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(dataResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.StructExcept(dataResp, "Data.EntryHash")
-	if err != nil {
-		return nil, err
-	}
-
-	return dataResp, nil
-
+// QueryDataEntry gets data entry by entry hash from data account
+func (c *AccumulateClient) QueryDataEntry(ctx context.Context, dataAccount *Params) (*QueryDataResponse, error) {
+	return query[QueryDataResponse](ctx, c, "query", dataAccount.URL, dataAccount, "Data.EntryHash")
 }
 
 // QueryDataSet gets data entries from data account
-func (c *AccumulateClient) QueryDataSet(dataAccount *Params) (*QueryDataSetResponse, error) {
-
-	dataEntriesResp := &QueryDataSetResponse{}
-
-	resp, err := c.Client.Call(context.Background(), "query-data-set", &dataAccount)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-
-	err = resp.GetObject(dataEntriesResp)
-	if err != nil {
-		return nil, fmt.Errorf("can not unmarshal api response: %s", err)
-	}
-
-	err = c.Validate.Struct(dataEntriesResp)
-	if err != nil {
-		log.Debug(err)
-		return nil, err
-	}
-
-	return dataEntriesResp, nil
-
+func (c *AccumulateClient) QueryDataSet(ctx context.Context, dataAccount *Params) (*QueryDataSetResponse, error) {
+	return query[QueryDataSetResponse](ctx, c, "query-data-set", dataAccount.URL, dataAccount)
 }
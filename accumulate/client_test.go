@@ -0,0 +1,480 @@
+package accumulate_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/accumulate/accumulatetest"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+func newClient(urls ...string) *accumulate.AccumulateClient {
+
+	client := accumulate.NewAccumulateClient(urls, 200*time.Millisecond)
+	client.Retry = &accumulate.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}
+
+	return client
+
+}
+
+func TestQueryToken(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddToken("acc://ACME", "ACME", 8, "100", "500")
+
+	res, err := newClient(node.URL).QueryToken(context.Background(), &accumulate.Params{URL: "acc://acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Data.Symbol != "ACME" || res.Data.Precision != 8 || res.Data.Issued != "100" || res.Data.SupplyLimit != "500" {
+		t.Errorf("unexpected token %+v", res.Data)
+	}
+
+}
+
+func TestQueryDataSet(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	for _, data := range []string{"a", "b", "c"} {
+		node.AddDataEntry("acc://data.acme", []byte(data))
+	}
+
+	res, err := newClient(node.URL).QueryDataSet(context.Background(), &accumulate.Params{URL: "acc://data.acme", Start: 1, Count: 5, Expand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Total != 3 || len(res.Items) != 2 {
+		t.Fatalf("expected 2 of 3 entries, got %d of %d", len(res.Items), res.Total)
+	}
+	if res.Items[0].Entry.Data[0] != "62" {
+		t.Errorf("expected hex encoded 'b', got %s", res.Items[0].Entry.Data[0])
+	}
+
+	latest, err := newClient(node.URL).QueryLatestDataEntry(context.Background(), &accumulate.Params{URL: "acc://data.acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Data.EntryHash != res.Items[1].EntryHash {
+		t.Errorf("expected latest entry %s, got %s", res.Items[1].EntryHash, latest.Data.EntryHash)
+	}
+
+}
+
+func TestQueryTxHistory(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddTx("acc://rewards.acme/tokens", &accumulate.QueryTokenTxResponse{
+		Type:   "sendTokens",
+		TxHash: "ff",
+		Data:   &accumulate.TokenTx{From: "acc://rewards.acme/tokens", To: []*accumulate.TokenTxTo{{URL: "acc://staker.acme/tokens", Amount: "100"}}},
+	})
+
+	res, err := newClient(node.URL).QueryTxHistory(context.Background(), &accumulate.Params{URL: "acc://rewards.acme/tokens", Count: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Total != 1 || len(res.Items) != 1 || res.Items[0].Data.To[0].Amount != "100" {
+		t.Errorf("unexpected tx history %+v", res)
+	}
+
+}
+
+func TestRetry(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddTokenAccount("acc://staker.acme/staking", "acc://ACME", "42")
+	node.FailNext("query", 2, accumulatetest.ErrCodeInternal, "Internal Error")
+
+	res, err := newClient(node.URL).QueryTokenAccount(context.Background(), &accumulate.Params{URL: "acc://staker.acme/staking"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Data.Balance != "42" {
+		t.Errorf("expected balance 42, got %s", res.Data.Balance)
+	}
+	if node.Calls("query") != 3 {
+		t.Errorf("expected 3 calls, got %d", node.Calls("query"))
+	}
+
+}
+
+func TestNotFoundIsNotRetried(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	_, err := newClient(node.URL).QueryTokenAccount(context.Background(), &accumulate.Params{URL: "acc://missing.acme/staking"})
+
+	var rpcErr *jsonrpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != accumulatetest.ErrCodeNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	var queryErr *accumulate.QueryError
+	if !errors.As(err, &queryErr) || queryErr.Method != "query" || queryErr.URL != "acc://missing.acme/staking" || queryErr.Code != accumulatetest.ErrCodeNotFound {
+		t.Errorf("expected query error with method, URL and code, got %#v", err)
+	}
+	if !accumulate.IsNotFound(err) {
+		t.Errorf("expected IsNotFound to report %v", err)
+	}
+	if node.Calls("query") != 1 {
+		t.Errorf("expected 1 call, got %d", node.Calls("query"))
+	}
+
+}
+
+func TestTimeout(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddTokenAccount("acc://slow.acme/staking", "acc://ACME", "1")
+	node.Delay("acc://slow.acme/staking", time.Second)
+
+	client := newClient(node.URL)
+	client.Retry = nil
+
+	_, err := client.QueryTokenAccount(context.Background(), &accumulate.Params{URL: "acc://slow.acme/staking"})
+	if err == nil || !accumulate.IsRetryable(err) {
+		t.Errorf("expected retryable timeout error, got %v", err)
+	}
+
+}
+
+func TestContextDeadline(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddTokenAccount("acc://slow.acme/staking", "acc://ACME", "1")
+	node.Delay("acc://slow.acme/staking", time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := accumulate.NewAccumulateClient([]string{node.URL}, time.Minute).QueryTokenAccount(ctx, &accumulate.Params{URL: "acc://slow.acme/staking"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+}
+
+func TestFailover(t *testing.T) {
+
+	broken := accumulatetest.NewServer()
+	defer broken.Close()
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	broken.FailURL("acc://ACME", accumulatetest.ErrCodeInternal, "Internal Error")
+	node.AddToken("acc://ACME", "ACME", 8, "100", "500")
+
+	client := newClient(broken.URL, node.URL)
+	client.Retry = nil
+
+	if _, err := client.QueryToken(context.Background(), &accumulate.Params{URL: "acc://ACME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	served := client.ServedCalls()
+	if served[node.URL] != 1 || served[broken.URL] != 0 {
+		t.Errorf("expected the call served by the second endpoint, got %v", served)
+	}
+
+}
+
+func TestV3QueryAccounts(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddToken("acc://ACME", "ACME", 8, "100", "500")
+	node.AddTokenAccount("acc://staker.acme/staking", "acc://ACME", "42")
+
+	client := accumulate.NewAccumulateV3Client(newClient(node.URL))
+
+	token, err := client.QueryToken(context.Background(), &accumulate.Params{URL: "acc://acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Data.Symbol != "ACME" || token.Data.Precision != 8 || token.Data.Issued != "100" || token.Data.SupplyLimit != "500" {
+		t.Errorf("unexpected token %+v", token.Data)
+	}
+
+	account, err := client.QueryTokenAccount(context.Background(), &accumulate.Params{URL: "acc://staker.acme/staking"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Data.Balance != "42" || account.Data.TokenURL != "acc://ACME" {
+		t.Errorf("unexpected token account %+v", account.Data)
+	}
+
+	_, err = client.QueryTokenAccount(context.Background(), &accumulate.Params{URL: "acc://missing.acme/staking"})
+
+	var queryErr *accumulate.QueryError
+	if !errors.As(err, &queryErr) || queryErr.URL != "acc://missing.acme/staking" || queryErr.Code != accumulatetest.ErrCodeV3NotFound {
+		t.Errorf("expected query error with URL and v3 code, got %#v", err)
+	}
+	if !accumulate.IsNotFound(err) {
+		t.Errorf("expected IsNotFound to report %v", err)
+	}
+	if node.Calls("query") != 3 {
+		t.Errorf("expected 3 calls, got %d", node.Calls("query"))
+	}
+
+}
+
+func TestV3QueryDataSet(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	for _, data := range []string{"a", "b", "c"} {
+		node.AddDataEntry("acc://data.acme", []byte(data))
+	}
+
+	client := accumulate.NewAccumulateV3Client(newClient(node.URL))
+
+	res, err := client.QueryDataSet(context.Background(), &accumulate.Params{URL: "acc://data.acme", Start: 1, Count: 5, Expand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Start != 1 || res.Total != 3 || res.Count != 2 || len(res.Items) != 2 {
+		t.Fatalf("expected 2 of 3 entries from 1, got %d of %d from %d", len(res.Items), res.Total, res.Start)
+	}
+	if res.Items[0].Entry.Type != "doubleHash" || res.Items[0].Entry.Data[0] != "62" {
+		t.Errorf("expected doubleHash entry of hex encoded 'b', got %+v", res.Items[0].Entry)
+	}
+	if res.Items[0].EntryHash == "" {
+		t.Error("expected entry hash")
+	}
+
+	latest, err := client.QueryLatestDataEntry(context.Background(), &accumulate.Params{URL: "acc://data.acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Data.EntryHash != res.Items[1].EntryHash || latest.Data.Entry.Data[0] != "63" {
+		t.Errorf("expected latest entry %s, got %+v", res.Items[1].EntryHash, latest.Data)
+	}
+
+	if _, err := client.QueryLatestDataEntry(context.Background(), &accumulate.Params{URL: "acc://empty.acme"}); !accumulate.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	// an entry without data does not fail the page, it is returned empty like v2 API does
+	node.AddEmptyDataEntry("acc://data.acme", "aa")
+	node.AddDataEntry("acc://data.acme", []byte("d"))
+
+	res, err = client.QueryDataSet(context.Background(), &accumulate.Params{URL: "acc://data.acme", Start: 3, Count: 5, Expand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 2 || res.Items[0].EntryHash != "aa" || len(res.Items[0].Entry.Data) != 0 || res.Items[1].Entry.Data[0] != "64" {
+		t.Errorf("expected empty entry followed by 'd', got %+v", res.Items)
+	}
+
+}
+
+func TestV3QueryTxHistory(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	tx := &accumulate.QueryTokenTxResponse{
+		Type:   "sendTokens",
+		TxHash: "ff",
+		Data:   &accumulate.TokenTx{From: "acc://rewards.acme/tokens", To: []*accumulate.TokenTxTo{{URL: "acc://staker.acme/tokens", Amount: "100"}}},
+	}
+	tx.Transaction.Header.Memo = "payout"
+	node.AddTx("acc://staker.acme/tokens", tx)
+	node.AddTx("acc://staker.acme/tokens", &accumulate.QueryTokenTxResponse{
+		Type:   "syntheticBurnTokens",
+		TxHash: "ee",
+		Data:   &accumulate.TokenTx{Amount: "5", IsRefund: true},
+	})
+
+	// signatures are not transactions, they are left out of the history
+	node.AddSignature("acc://staker.acme/tokens", "dd")
+
+	client := accumulate.NewAccumulateV3Client(newClient(node.URL))
+
+	res, err := client.QueryTxHistory(context.Background(), &accumulate.Params{URL: "acc://staker.acme/tokens", Count: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Total != 3 || res.Count != 2 || len(res.Items) != 2 {
+		t.Fatalf("expected 2 transactions of 3 entries, got %d of %d", len(res.Items), res.Total)
+	}
+
+	send := res.Items[0]
+	if send.Type != "sendTokens" || send.TxHash != "ff" || send.TxID != "acc://ff@rewards.acme/tokens" || send.Transaction.Header.Memo != "payout" {
+		t.Errorf("unexpected transaction %+v", send)
+	}
+	if send.Data.From != "acc://rewards.acme/tokens" || len(send.Data.To) != 1 || send.Data.To[0].URL != "acc://staker.acme/tokens" || send.Data.To[0].Amount != "100" {
+		t.Errorf("unexpected transaction data %+v", send.Data)
+	}
+
+	burn := res.Items[1]
+	if burn.Type != "syntheticBurnTokens" || burn.TxHash != "ee" || burn.Data.Amount != "5" || !burn.Data.IsRefund {
+		t.Errorf("unexpected burn %+v", burn.Data)
+	}
+
+	page, err := client.QueryTxHistory(context.Background(), &accumulate.Params{URL: "acc://staker.acme/tokens", Start: 1, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Start != 1 || page.Total != 3 || len(page.Items) != 1 || page.Items[0].TxHash != "ee" {
+		t.Errorf("expected the second transaction, got %+v", page)
+	}
+
+}
+
+func TestV3InvalidRecord(t *testing.T) {
+
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"recordType":"range","records":[]}}`))
+	}))
+	defer node.Close()
+
+	rpc := newClient(node.URL)
+	rpc.Retry = nil
+
+	_, err := accumulate.NewAccumulateV3Client(rpc).QueryToken(context.Background(), &accumulate.Params{URL: "acc://ACME"})
+
+	var queryErr *accumulate.QueryError
+	if !errors.As(err, &queryErr) || queryErr.URL != "acc://ACME" || !strings.Contains(err.Error(), "invalid api response") {
+		t.Errorf("expected invalid api response error, got %v", err)
+	}
+
+}
+
+func TestV3HealthChecks(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	accumulate.NewAccumulateV3Client(newClient(node.URL)).StartHealthChecks(ctx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for node.Calls("node-info") < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if node.Calls("node-info") < 2 {
+		t.Errorf("expected repeated node-info health checks, got %d", node.Calls("node-info"))
+	}
+	if node.Calls("version") != 0 {
+		t.Errorf("expected no v2 version calls, got %d", node.Calls("version"))
+	}
+
+}
+
+func TestCircuitBreaker(t *testing.T) {
+
+	node := accumulatetest.NewServer()
+	defer node.Close()
+
+	node.AddToken("acc://ACME", "ACME", 8, "100", "500")
+	node.FailURL("acc://ACME", accumulatetest.ErrCodeInternal, "Internal Error")
+
+	cooldown := 50 * time.Millisecond
+
+	client := newClient(node.URL)
+	client.Retry = nil
+	client.SetCircuitBreaker(3, cooldown)
+
+	query := func() error {
+		_, err := client.QueryToken(context.Background(), &accumulate.Params{URL: "acc://ACME"})
+		return err
+	}
+
+	// consecutive retryable failures open the breaker
+	for i := 0; i < 3; i++ {
+		if err := query(); err == nil || errors.Is(err, accumulate.ErrCircuitOpen) {
+			t.Fatalf("call %d: expected node error, got %v", i+1, err)
+		}
+	}
+	if !client.Endpoints[0].Breaker.IsOpen() {
+		t.Fatal("expected open breaker after 3 failures")
+	}
+
+	if err := query(); !errors.Is(err, accumulate.ErrCircuitOpen) {
+		t.Errorf("expected circuit open error, got %v", err)
+	}
+	if node.Calls("query") != 3 {
+		t.Errorf("expected rejected call not to reach the node, got %d calls", node.Calls("query"))
+	}
+
+	// failed trial after cooldown opens the breaker again
+	time.Sleep(cooldown)
+	if err := query(); err == nil || errors.Is(err, accumulate.ErrCircuitOpen) {
+		t.Errorf("expected failed trial call, got %v", err)
+	}
+	if err := query(); !errors.Is(err, accumulate.ErrCircuitOpen) {
+		t.Errorf("expected circuit open error after failed trial, got %v", err)
+	}
+	if node.Calls("query") != 4 {
+		t.Errorf("expected a single trial call, got %d calls", node.Calls("query"))
+	}
+
+	// a single trial goes through while the breaker is half-open, its success closes the breaker
+	time.Sleep(cooldown)
+	node.FailURL("acc://ACME", 0, "")
+	node.Delay("acc://ACME", 100*time.Millisecond)
+
+	trial := make(chan error, 1)
+	go func() {
+		trial <- query()
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for node.Calls("query") < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := query(); !errors.Is(err, accumulate.ErrCircuitOpen) {
+		t.Errorf("expected circuit open error while the trial is in flight, got %v", err)
+	}
+	if err := <-trial; err != nil {
+		t.Fatalf("expected successful trial, got %v", err)
+	}
+	if node.Calls("query") != 5 {
+		t.Errorf("expected exactly one half-open call, got %d calls", node.Calls("query"))
+	}
+
+	node.Delay("acc://ACME", 0)
+	if client.Endpoints[0].Breaker.IsOpen() {
+		t.Error("expected closed breaker after successful trial")
+	}
+	if err := query(); err != nil {
+		t.Errorf("expected call through closed breaker, got %v", err)
+	}
+	if node.Calls("query") != 6 {
+		t.Errorf("expected 6 calls, got %d", node.Calls("query"))
+	}
+
+}
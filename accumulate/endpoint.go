@@ -0,0 +1,233 @@
+package accumulate
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// latencyWeight is the weight of the latest measurement in the moving average of endpoint latency
+const latencyWeight = 0.3
+
+// Endpoint is an Accumulate node API endpoint with its health state
+type Endpoint struct {
+	URL    string
+	Client jsonrpc.RPCClient
+	// Breaker stops calls to a failing endpoint, nil disables it
+	Breaker *CircuitBreaker
+	// HealthMethod is a lightweight method without params called by health checks
+	HealthMethod string
+
+	mu        sync.RWMutex
+	healthy   bool
+	latency   time.Duration
+	lastError string
+	lastCheck time.Time
+
+	served int64
+}
+
+// EndpointStatus is health state of endpoint, Latency is the moving average in seconds.
+// LastCheck is nil until the first health check.
+type EndpointStatus struct {
+	URL       string     `json:"url"`
+	Healthy   bool       `json:"healthy"`
+	Latency   float64    `json:"latency"`
+	LastError string     `json:"lastError,omitempty"`
+	LastCheck *time.Time `json:"lastCheck"`
+	Served    int64      `json:"served"`
+}
+
+// NewEndpoint constructs endpoint, it is considered healthy until the first failure
+func NewEndpoint(url string, client jsonrpc.RPCClient, healthMethod string) *Endpoint {
+
+	return &Endpoint{URL: url, Client: client, HealthMethod: healthMethod, healthy: true}
+
+}
+
+// Status returns health state of endpoint
+func (e *Endpoint) Status() *EndpointStatus {
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	res := &EndpointStatus{
+		URL:       e.URL,
+		Healthy:   e.healthy,
+		Latency:   e.latency.Seconds(),
+		LastError: e.lastError,
+		Served:    atomic.LoadInt64(&e.served),
+	}
+	if !e.lastCheck.IsZero() {
+		lastCheck := e.lastCheck
+		res.LastCheck = &lastCheck
+	}
+
+	return res
+
+}
+
+// Served returns number of calls served by endpoint
+func (e *Endpoint) Served() int64 {
+	return atomic.LoadInt64(&e.served)
+}
+
+// success records successful call and its latency
+func (e *Endpoint) success(latency time.Duration) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(e.latency))
+	}
+
+	if !e.healthy {
+		log.Info("endpoint ", e.URL, " is healthy again")
+	}
+
+	e.healthy = true
+	e.lastError = ""
+
+}
+
+// failure marks endpoint unhealthy
+func (e *Endpoint) failure(err error) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.healthy {
+		log.Warn("endpoint ", e.URL, " is unhealthy: ", err)
+	}
+
+	e.healthy = false
+	e.lastError = err.Error()
+
+}
+
+// available reports whether endpoint may be called now
+func (e *Endpoint) available() bool {
+
+	return e.Breaker == nil || e.Breaker.Ready()
+
+}
+
+// check calls lightweight health method and updates endpoint health
+func (e *Endpoint) check(ctx context.Context) {
+
+	start := time.Now()
+
+	resp, err := e.Client.Call(ctx, e.HealthMethod)
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	e.mu.Lock()
+	e.lastCheck = time.Now()
+	e.mu.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			e.failure(err)
+		}
+		return
+	}
+
+	e.success(time.Since(start))
+
+}
+
+// candidates returns endpoints in the order they should be tried:
+// healthy endpoints by latency, then unhealthy ones as the last resort
+func (c *AccumulateClient) candidates() []*Endpoint {
+
+	type candidate struct {
+		endpoint  *Endpoint
+		healthy   bool
+		available bool
+		latency   time.Duration
+	}
+
+	list := make([]*candidate, 0, len(c.Endpoints))
+	for _, e := range c.Endpoints {
+		e.mu.RLock()
+		list = append(list, &candidate{endpoint: e, healthy: e.healthy, available: e.available(), latency: e.latency})
+		e.mu.RUnlock()
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].available != list[j].available {
+			return list[i].available
+		}
+		if list[i].healthy != list[j].healthy {
+			return list[i].healthy
+		}
+		return list[i].latency < list[j].latency
+	})
+
+	res := make([]*Endpoint, len(list))
+	for i, cand := range list {
+		res[i] = cand.endpoint
+	}
+
+	return res
+
+}
+
+// StartHealthChecks checks every endpoint each interval until ctx is canceled
+func (c *AccumulateClient) StartHealthChecks(ctx context.Context, interval time.Duration) {
+
+	go func() {
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+
+			for _, e := range c.Endpoints {
+				e.check(ctx)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+		}
+
+	}()
+
+}
+
+// EndpointsStatus returns health state of every endpoint
+func (c *AccumulateClient) EndpointsStatus() []*EndpointStatus {
+
+	res := make([]*EndpointStatus, 0, len(c.Endpoints))
+	for _, e := range c.Endpoints {
+		res = append(res, e.Status())
+	}
+
+	return res
+
+}
+
+// ServedCalls returns number of calls served by every endpoint
+func (c *AccumulateClient) ServedCalls() map[string]int64 {
+
+	res := make(map[string]int64, len(c.Endpoints))
+	for _, e := range c.Endpoints {
+		res[e.URL] = e.Served()
+	}
+
+	return res
+
+}
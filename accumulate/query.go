@@ -0,0 +1,88 @@
+package accumulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// QueryError is returned by every query, it wraps the cause with the called method and URL
+type QueryError struct {
+	Method string
+	URL    string
+	// Code is JSON-RPC error code, 0 if the node did not return an RPC error
+	Code int
+	Err  error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// newQueryError wraps err of method call for url
+func newQueryError(method, url string, err error) *QueryError {
+
+	res := &QueryError{Method: method, URL: url, Err: err}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		res.Code = rpcErr.Code
+	}
+
+	return res
+
+}
+
+// query calls JSON-RPC method, unmarshals the result into T and validates it, skipping except fields.
+// Every error is returned as *QueryError, url identifies the queried account in errors.
+func query[T any](ctx context.Context, c *AccumulateClient, method, url string, params interface{}, except ...string) (*T, error) {
+
+	resp, err := c.call(ctx, method, params)
+	if err != nil {
+		return nil, newQueryError(method, url, err)
+	}
+
+	if resp.Error != nil {
+		return nil, newQueryError(method, url, resp.Error)
+	}
+
+	res := new(T)
+
+	err = resp.GetObject(res)
+	if err != nil {
+		return nil, newQueryError(method, url, fmt.Errorf("can not unmarshal api response: %w", err))
+	}
+
+	if len(except) > 0 {
+		err = c.Validate.StructExcept(res, except...)
+	} else {
+		err = c.Validate.Struct(res)
+	}
+	if err != nil {
+		return nil, newQueryError(method, url, fmt.Errorf("invalid api response: %w", err))
+	}
+
+	return res, nil
+
+}
+
+// NotFoundCodes lists JSON-RPC error codes returned for missing accounts and entries by v2 and v3 APIs
+var NotFoundCodes = map[int]bool{
+	-32807: true, // v2 not found
+	-33404: true, // v3 not found
+}
+
+// IsNotFound reports whether query failed because the account or entry does not exist
+func IsNotFound(err error) bool {
+
+	var queryErr *QueryError
+
+	return errors.As(err, &queryErr) && NotFoundCodes[queryErr.Code]
+
+}
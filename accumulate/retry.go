@@ -0,0 +1,307 @@
+package accumulate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/metrics"
+	"github.com/labstack/gommon/log"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// RetryableCodes lists JSON-RPC error codes caused by node-side conditions that may pass on retry.
+// Validation, not found and other request errors are returned immediately.
+var RetryableCodes = map[int]bool{
+	-32603: true, // JSON-RPC internal error
+	-32800: true, // Accumulate internal error
+	-32801: true, // Accumulate dispatch error
+	-32808: true, // request canceled by the node
+	// v3 API returns -33000 minus error status
+	-33500: true, // internal error
+	-33504: true, // node is not ready
+	-33506: true, // no peer available for the request
+}
+
+// ErrCircuitOpen is returned without calling the node while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open, node is failing")
+
+// ErrNoEndpoints is returned when the client has no endpoints configured
+var ErrNoEndpoints = errors.New("no Accumulate API endpoints configured")
+
+type RetryPolicy struct {
+	// MaxAttempts includes the first call, 1 disables retries
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is the random fraction (0..1) subtracted from every backoff
+	Jitter float64
+}
+
+// Backoff returns jittered delay before the given retry (1 for the first retry)
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	delay -= delay * p.Jitter * rand.Float64()
+
+	return time.Duration(delay)
+
+}
+
+// IsRetryable reports whether error of a call may pass on retry
+func IsRetryable(err error) bool {
+
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return RetryableCodes[rpcErr.Code]
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= http.StatusInternalServerError
+	}
+
+	// transport errors: timeouts, refused connections, broken responses
+	return true
+
+}
+
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker stops calls to a node after FailureThreshold consecutive retryable failures.
+// After Cooldown a single trial call is let through: success closes the circuit, failure opens it again.
+type CircuitBreaker struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker constructs closed circuit breaker
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+
+	return &CircuitBreaker{FailureThreshold: failureThreshold, Cooldown: cooldown}
+
+}
+
+// Allow returns ErrCircuitOpen if the call must not be made
+func (b *CircuitBreaker) Allow() error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// trial call is in flight
+		return ErrCircuitOpen
+	}
+
+	return nil
+
+}
+
+// Success records successful call
+func (b *CircuitBreaker) Success() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = circuitClosed
+	b.failures = 0
+
+}
+
+// Failure records failed call
+func (b *CircuitBreaker) Failure() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	if b.state == circuitHalfOpen || b.failures >= b.FailureThreshold {
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	}
+
+}
+
+// Abort records call canceled by the caller, it says nothing about the node
+func (b *CircuitBreaker) Abort() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// let the next call be the trial
+	if b.state == circuitHalfOpen {
+		b.state = circuitOpen
+	}
+
+}
+
+// Ready reports whether Allow would let a call through, without taking the trial call
+func (b *CircuitBreaker) Ready() bool {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == circuitClosed || b.state == circuitOpen && time.Since(b.openedAt) >= b.Cooldown
+
+}
+
+// IsOpen reports whether calls are currently rejected
+func (b *CircuitBreaker) IsOpen() bool {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state != circuitClosed
+
+}
+
+// call performs JSON-RPC call with rate limiting, failover, retries and circuit breakers.
+// RPC errors are returned in the response, as jsonrpc client does.
+func (c *AccumulateClient) call(ctx context.Context, method string, params interface{}) (*jsonrpc.RPCResponse, error) {
+
+	attempts := 1
+	if c.Retry != nil && c.Retry.MaxAttempts > 1 {
+		attempts = c.Retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+
+		resp, err := c.callOnce(ctx, method, params)
+		if err != nil && ctx.Err() != nil {
+			// transport errors hide the cause, report cancellation by the caller
+			return nil, ctx.Err()
+		}
+
+		callErr := err
+		if callErr == nil && resp != nil && resp.Error != nil {
+			callErr = resp.Error
+		}
+
+		if !IsRetryable(callErr) || attempt >= attempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		backoff := c.Retry.Backoff(attempt)
+		log.Warnf("%s() call failed (attempt %d/%d), retrying in %s: %s", method, attempt, attempts, backoff, callErr)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+	}
+
+}
+
+// callOnce tries endpoints from the healthiest one, failing over to the next endpoint on retryable errors
+func (c *AccumulateClient) callOnce(ctx context.Context, method string, params interface{}) (*jsonrpc.RPCResponse, error) {
+
+	var resp *jsonrpc.RPCResponse
+	err := ErrNoEndpoints
+
+	for _, e := range c.candidates() {
+
+		resp, err = c.callEndpoint(ctx, e, method, params)
+
+		callErr := err
+		if callErr == nil && resp != nil && resp.Error != nil {
+			callErr = resp.Error
+		}
+
+		if ctx.Err() != nil || !IsRetryable(callErr) && !errors.Is(callErr, ErrCircuitOpen) {
+			return resp, err
+		}
+
+		if len(c.Endpoints) > 1 {
+			log.Warn(method, "() call failed on ", e.URL, ", failing over: ", callErr)
+		}
+
+	}
+
+	return resp, err
+
+}
+
+// callEndpoint makes a single call through the rate limiter and circuit breaker of endpoint
+func (c *AccumulateClient) callEndpoint(ctx context.Context, e *Endpoint, method string, params interface{}) (*jsonrpc.RPCResponse, error) {
+
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Breaker != nil {
+		if err := e.Breaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+
+	resp, err := e.Client.Call(ctx, method, params)
+
+	callErr := err
+	if callErr == nil && resp != nil && resp.Error != nil {
+		callErr = resp.Error
+	}
+
+	metrics.RPCCalls.WithLabelValues(method).Inc()
+	if callErr != nil {
+		metrics.RPCErrors.WithLabelValues(method).Inc()
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		if e.Breaker != nil {
+			e.Breaker.Abort()
+		}
+	case IsRetryable(callErr):
+		if e.Breaker != nil {
+			e.Breaker.Failure()
+		}
+		e.failure(callErr)
+	default:
+		// the node responded, even if with a request error
+		if e.Breaker != nil {
+			e.Breaker.Success()
+		}
+		e.success(time.Since(start))
+		atomic.AddInt64(&e.served, 1)
+	}
+
+	return resp, err
+
+}
@@ -0,0 +1,266 @@
+package accumulate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccumulateV3Client is the Accumulate v3 API client.
+// It maps v3 records to responses of v2 client, so the service does not depend on the API version.
+type AccumulateV3Client struct {
+	rpc *AccumulateClient
+}
+
+type V3QueryParams struct {
+	Scope string   `json:"scope"`
+	Query *V3Query `json:"query"`
+}
+
+type V3Query struct {
+	QueryType string   `json:"queryType"`
+	Name      string   `json:"name,omitempty"`
+	Range     *V3Range `json:"range,omitempty"`
+}
+
+type V3Range struct {
+	Start  int64  `json:"start"`
+	Count  *int64 `json:"count,omitempty"`
+	Expand bool   `json:"expand,omitempty"`
+}
+
+type V3AccountRecord[T any] struct {
+	RecordType string `json:"recordType" validate:"required,eq=account"`
+	Account    T      `json:"account" validate:"required"`
+}
+
+type V3RecordRange struct {
+	RecordType string                `json:"recordType" validate:"required,eq=range"`
+	Records    []*V3ChainEntryRecord `json:"records"`
+	Start      int64                 `json:"start"`
+	Total      int64                 `json:"total"`
+}
+
+type V3ChainEntryRecord struct {
+	RecordType string           `json:"recordType" validate:"required,eq=chainEntry"`
+	Account    string           `json:"account"`
+	Name       string           `json:"name"`
+	Index      int64            `json:"index"`
+	Entry      string           `json:"entry" validate:"required"`
+	Value      *V3MessageRecord `json:"value"`
+}
+
+type V3MessageRecord struct {
+	RecordType string `json:"recordType"`
+	ID         string `json:"id"`
+	Message    struct {
+		Type        string         `json:"type"`
+		Transaction *V3Transaction `json:"transaction"`
+	} `json:"message"`
+	Status string `json:"status"`
+}
+
+type V3Transaction struct {
+	Header struct {
+		Principal string `json:"principal"`
+		Memo      string `json:"memo"`
+	} `json:"header"`
+	Body *V3TransactionBody `json:"body"`
+}
+
+type V3TransactionBody struct {
+	Type  string `json:"type"`
+	Entry *struct {
+		Type string   `json:"type"`
+		Data []string `json:"data"`
+	} `json:"entry"`
+	To       []*TokenTxTo `json:"to"`
+	Cause    string       `json:"cause"`
+	Source   string       `json:"source"`
+	Token    string       `json:"token"`
+	Amount   string       `json:"amount"`
+	IsRefund bool         `json:"isRefund"`
+}
+
+// NewAccumulateV3Client constructs v3 API client on top of rpc client, which endpoints must serve v3 API.
+// The rpc client keeps routing calls with its rate limit, retries and circuit breakers.
+func NewAccumulateV3Client(rpc *AccumulateClient) *AccumulateV3Client {
+
+	for _, e := range rpc.Endpoints {
+		e.HealthMethod = "node-info"
+	}
+
+	return &AccumulateV3Client{rpc: rpc}
+
+}
+
+// queryV3 calls v3 query method for scope
+func queryV3[T any](ctx context.Context, c *AccumulateV3Client, scope string, q *V3Query) (*T, error) {
+	return query[T](ctx, c.rpc, "query", scope, &V3QueryParams{Scope: scope, Query: q})
+}
+
+// queryRange queries entries of a chain, or data entries if name is empty
+func (c *AccumulateV3Client) queryRange(ctx context.Context, params *Params, queryType, name string) (*V3RecordRange, error) {
+
+	rng := &V3Range{Start: params.Start, Expand: true}
+	if params.Count > 0 {
+		rng.Count = &params.Count
+	}
+
+	return queryV3[V3RecordRange](ctx, c, params.URL, &V3Query{QueryType: queryType, Name: name, Range: rng})
+
+}
+
+// QueryToken gets Token info
+func (c *AccumulateV3Client) QueryToken(ctx context.Context, token *Params) (*QueryTokenResponse, error) {
+
+	record, err := queryV3[V3AccountRecord[*Token]](ctx, c, token.URL, &V3Query{QueryType: "default"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryTokenResponse{Data: record.Account}, nil
+
+}
+
+// QueryTokenAccount gets Token Account info
+func (c *AccumulateV3Client) QueryTokenAccount(ctx context.Context, account *Params) (*QueryTokenAccountResponse, error) {
+
+	record, err := queryV3[V3AccountRecord[*TokenAccount]](ctx, c, account.URL, &V3Query{QueryType: "default"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryTokenAccountResponse{Data: record.Account}, nil
+
+}
+
+// QueryLatestDataEntry gets latest data entry from data account
+func (c *AccumulateV3Client) QueryLatestDataEntry(ctx context.Context, dataAccount *Params) (*QueryDataResponse, error) {
+
+	record, err := queryV3[V3ChainEntryRecord](ctx, c, dataAccount.URL, &V3Query{QueryType: "data"})
+	if err != nil {
+		return nil, err
+	}
+
+	// v2 API rejects the latest entry without data, so does v3 client
+	res := &QueryDataResponse{Data: dataEntry(record)}
+	if err := c.rpc.Validate.Struct(res); err != nil {
+		return nil, newQueryError("query", dataAccount.URL, fmt.Errorf("invalid api response: %w", err))
+	}
+
+	return res, nil
+
+}
+
+// QueryDataSet gets data entries from data account, entries without data are returned empty like v2 API does
+func (c *AccumulateV3Client) QueryDataSet(ctx context.Context, dataAccount *Params) (*QueryDataSetResponse, error) {
+
+	records, err := c.queryRange(ctx, dataAccount, "data", "")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &QueryDataSetResponse{Start: records.Start, Count: int64(len(records.Records)), Total: records.Total}
+
+	for _, record := range records.Records {
+		res.Items = append(res.Items, dataEntry(record))
+	}
+
+	return res, nil
+
+}
+
+// QueryTxHistory gets tx history of account from its main chain.
+// Entries that are not transactions, e.g. signatures, are left out, so a page may have less items than requested.
+func (c *AccumulateV3Client) QueryTxHistory(ctx context.Context, account *Params) (*QueryTxHistoryResponse, error) {
+
+	records, err := c.queryRange(ctx, account, "chain", "main")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &QueryTxHistoryResponse{Start: records.Start, Total: records.Total}
+
+	for _, record := range records.Records {
+		if tx := tokenTx(record); tx != nil {
+			res.Items = append(res.Items, tx)
+		}
+	}
+	res.Count = int64(len(res.Items))
+
+	return res, nil
+
+}
+
+func (c *AccumulateV3Client) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	c.rpc.StartHealthChecks(ctx, interval)
+}
+
+func (c *AccumulateV3Client) EndpointsStatus() []*EndpointStatus {
+	return c.rpc.EndpointsStatus()
+}
+
+func (c *AccumulateV3Client) ServedCalls() map[string]int64 {
+	return c.rpc.ServedCalls()
+}
+
+// dataEntry converts expanded chain entry of data account into v2 data entry.
+// An entry without data is returned without type and data, consumers skip it as a parse failure.
+func dataEntry(record *V3ChainEntryRecord) *DataEntry {
+
+	entry := &DataEntry{EntryHash: record.Entry}
+
+	tx := record.Value.transaction()
+	if tx == nil || tx.Body.Entry == nil {
+		return entry
+	}
+
+	entry.Entry.Type = tx.Body.Entry.Type
+	entry.Entry.Data = tx.Body.Entry.Data
+
+	return entry
+
+}
+
+// tokenTx converts expanded chain entry into v2 token transaction, or returns nil if it is not a transaction
+func tokenTx(record *V3ChainEntryRecord) *QueryTokenTxResponse {
+
+	tx := record.Value.transaction()
+	if tx == nil {
+		return nil
+	}
+
+	res := &QueryTokenTxResponse{Type: tx.Body.Type, TxHash: record.Entry, TxID: record.Value.ID}
+
+	// transaction ID is acc://<hash>@<principal>
+	if hash, _, ok := strings.Cut(strings.TrimPrefix(record.Value.ID, "acc://"), "@"); ok {
+		res.TxHash = hash
+	}
+
+	res.Data = &TokenTx{
+		From:     tx.Header.Principal,
+		To:       tx.Body.To,
+		Cause:    tx.Body.Cause,
+		Source:   tx.Body.Source,
+		Token:    tx.Body.Token,
+		Amount:   tx.Body.Amount,
+		IsRefund: tx.Body.IsRefund,
+	}
+	res.Transaction.Header.Memo = tx.Header.Memo
+
+	return res
+
+}
+
+// transaction returns transaction of message record, or nil if it is not an expanded transaction
+func (r *V3MessageRecord) transaction() *V3Transaction {
+
+	if r == nil || r.Message.Transaction == nil || r.Message.Transaction.Body == nil {
+		return nil
+	}
+
+	return r.Message.Transaction
+
+}
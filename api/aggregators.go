@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AggregatorSupplyResponse is the JSON supply format of CoinGecko and CoinMarketCap, amounts are numbers in tokens
+type AggregatorSupplyResponse struct {
+	TotalSupply       json.Number `json:"total_supply"`
+	CirculatingSupply json.Number `json:"circulating_supply"`
+	// MaxSupply is null for tokens without supply limit
+	MaxSupply *json.Number `json:"max_supply"`
+}
+
+// getCoinGeckoSupply returns ACME supply in CoinGecko format
+func (api *API) getCoinGeckoSupply(c echo.Context) error {
+
+	return api.getAggregatorSupply(c, api.Config.Aggregators.CoinGeckoDecimals)
+
+}
+
+// getCoinMarketCapSupply returns ACME supply in CoinMarketCap format
+func (api *API) getCoinMarketCapSupply(c echo.Context) error {
+
+	return api.getAggregatorSupply(c, api.Config.Aggregators.CoinMarketCapDecimals)
+
+}
+
+// getAggregatorSupply returns ACME supply rounded to decimals, either a single plain text number selected by
+// "kind" path param or all of them in JSON
+func (api *API) getAggregatorSupply(c echo.Context, decimals int) error {
+
+	kind := c.Param("kind")
+
+	switch kind {
+	case "", "total", "circulating", "max":
+	default:
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: fmt.Sprintf("'kind' expected to be one of total, circulating, max, '%s' received", kind)})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	supply := api.newACMESupplyResponse(snapshot)
+
+	res := &AggregatorSupplyResponse{
+		TotalSupply:       json.Number(supply.Total.TokensRounded(supply.Precision, decimals)),
+		CirculatingSupply: json.Number(supply.Circulating.TokensRounded(supply.Precision, decimals)),
+	}
+	if supply.Max.Sign() > 0 {
+		max := json.Number(supply.Max.TokensRounded(supply.Precision, decimals))
+		res.MaxSupply = &max
+	}
+
+	switch kind {
+	case "total":
+		return c.String(http.StatusOK, res.TotalSupply.String())
+	case "circulating":
+		return c.String(http.StatusOK, res.CirculatingSupply.String())
+	case "max":
+		if res.MaxSupply == nil {
+			return c.JSON(http.StatusNotFound, &ErrorResponse{Code: http.StatusNotFound, Error: "token has no supply limit"})
+		}
+		return c.String(http.StatusOK, res.MaxSupply.String())
+	}
+
+	return c.JSON(http.StatusOK, res)
+
+}
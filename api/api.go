@@ -1,12 +1,17 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/config"
 	"github.com/AccumulateNetwork/metrics-api/schema"
 	"github.com/AccumulateNetwork/metrics-api/store"
 	"github.com/go-playground/validator/v10"
@@ -21,6 +26,10 @@ const DefaultPaginationCount = 10
 type API struct {
 	HTTP     *echo.Echo
 	Validate *validator.Validate
+	Config   *config.Config
+	Store    store.Store
+	// Client is queried for live health of Accumulate endpoints
+	Client accumulate.Client
 }
 
 type PaginationParams struct {
@@ -28,6 +37,13 @@ type PaginationParams struct {
 	Count int `json:"count" validate:"min=0"`
 }
 
+// StakersParams filter and sort stakers list
+type StakersParams struct {
+	store.StakingFilter
+	Sort  string
+	Order string
+}
+
 type PaginationResponse struct {
 	PaginationParams
 	Total int `json:"total"`
@@ -39,28 +55,70 @@ type ErrorResponse struct {
 	Error  string `json:"error"`
 }
 type SupplyResponse struct {
+	URL string `json:"url"`
 	schema.ACME
-	Staked            int64      `json:"staked"`
-	Circulating       int64      `json:"circulating"`
-	TotalTokens       float64    `json:"totalTokens"`
-	MaxTokens         float64    `json:"maxTokens"`
-	StakedTokens      float64    `json:"stakedTokens"`
-	CirculatingTokens float64    `json:"circulatingTokens"`
-	UpdatedAt         *time.Time `json:"updatedAt"`
+	SnapshotID        int64         `json:"snapshotId"`
+	Staked            schema.Amount `json:"staked"`
+	Locked            schema.Amount `json:"locked"`
+	Circulating       schema.Amount `json:"circulating"`
+	TotalTokens       string        `json:"totalTokens"`
+	MaxTokens         string        `json:"maxTokens"`
+	StakedTokens      string        `json:"stakedTokens"`
+	LockedTokens      string        `json:"lockedTokens"`
+	CirculatingTokens string        `json:"circulatingTokens"`
+	// LockedAccounts breaks down locked supply of ACME by configured account
+	LockedAccounts []*LockedAccountResponse `json:"lockedAccounts,omitempty"`
+	// Methodology explains how circulating supply is calculated
+	Methodology string     `json:"methodology"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+}
+
+type LockedAccountResponse struct {
+	*schema.LockedAccount
+	BalanceTokens string `json:"balanceTokens"`
 }
 
 type StakingResponse struct {
 	schema.ValidatorsNumber
+	SnapshotID int64 `json:"snapshotId"`
 }
 type StakersResponse struct {
 	Result []*schema.StakingRecord `json:"result"`
 	PaginationResponse
+	SnapshotID int64 `json:"snapshotId"`
+}
+
+type IngestionResponse struct {
+	*schema.CycleStats
+	// EndpointsStatus is the current health of Accumulate endpoints, not only of the latest cycle
+	EndpointsStatus []*accumulate.EndpointStatus `json:"endpointsStatus"`
+	SnapshotID      int64                        `json:"snapshotId"`
+	UpdatedAt       time.Time                    `json:"updatedAt"`
+}
+
+type StakerResponse struct {
+	*schema.StakingRecord
+	BalanceTokens string `json:"balanceTokens"`
+	// Rank is position by balance among all stakers, starting from 1
+	Rank int `json:"rank"`
+	// Share is fraction of total stake (0..1)
+	Share float64 `json:"share"`
+	// Validator is the staker that delegated staker delegates to
+	Validator *schema.StakingRecord `json:"validator,omitempty"`
+	// Yield is realised yield of the staker over YieldWindows
+	Yield      []*schema.Yield `json:"yield"`
+	SnapshotID int64           `json:"snapshotId"`
+}
+
+type StakerHistoryResponse struct {
+	Identity string                         `json:"identity"`
+	Result   []*schema.StakingRecordVersion `json:"result"`
 }
 
-// StartAPI configures and starts REST API server
-func StartAPI(port int) error {
+// NewAPI configures REST API server
+func NewAPI(cfg *config.Config, st store.Store, client accumulate.Client) *API {
 
-	api := &API{}
+	api := &API{Config: cfg, Store: st, Client: client}
 
 	api.HTTP = echo.New()
 	api.HTTP.HideBanner = true
@@ -80,6 +138,16 @@ func StartAPI(port int) error {
 	// https://echo.labstack.com/middleware/logger/
 	api.HTTP.Use(middleware.Logger())
 
+	// Prometheus metrics of HTTP requests
+	api.HTTP.Use(MetricsMiddleware())
+
+	// health checks
+	api.HTTP.GET("/health/live", api.getLiveness)
+	api.HTTP.GET("/health/ready", api.getReadiness)
+
+	// Prometheus metrics
+	api.HTTP.GET("/metrics", MetricsHandler())
+
 	// v1 public metrics API
 	api.HTTP.GET("/v1", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Accumulate Metrics API")
@@ -87,13 +155,47 @@ func StartAPI(port int) error {
 	publicAPI := api.HTTP.Group("/v1")
 
 	publicAPI.GET("/supply", api.getSupply)
+	publicAPI.GET("/supply/history", api.getSupplyHistory)
+	publicAPI.GET("/supply/flows", api.getSupplyFlows)
+	publicAPI.GET("/supply/coingecko", api.getCoinGeckoSupply)
+	publicAPI.GET("/supply/coingecko/:kind", api.getCoinGeckoSupply)
+	publicAPI.GET("/supply/coinmarketcap", api.getCoinMarketCapSupply)
+	publicAPI.GET("/supply/coinmarketcap/:kind", api.getCoinMarketCapSupply)
 	publicAPI.GET("/supply/:filter", api.getSupply)
+	publicAPI.GET("/tokens", api.getTokens)
+	publicAPI.GET("/tokens/:url/supply", api.getTokenSupply)
 	publicAPI.GET("/staking", api.getStaking)
 	publicAPI.GET("/staking/stakers", api.getStakers)
+	publicAPI.GET("/staking/stakers/:identity", api.getStaker)
+	publicAPI.GET("/staking/stakers/:identity/history", api.getStakerHistory)
+	publicAPI.GET("/staking/stakers/:identity/rewards", api.getStakerRewards)
+	publicAPI.GET("/staking/rewards", api.getRewards)
+	publicAPI.GET("/staking/yield", api.getYield)
+	publicAPI.GET("/staking/validators", api.getValidators)
+	publicAPI.GET("/staking/validators/:identity/delegators", api.getDelegators)
+	publicAPI.GET("/ingestion", api.getIngestion)
+	publicAPI.GET("/config", api.getConfig)
+
+	return api
 
-	api.HTTP.Logger.Fatal(api.HTTP.Start(":" + strconv.Itoa(port)))
+}
 
-	return nil
+// Start starts REST API server and blocks until it is stopped, graceful shutdown returns nil
+func (api *API) Start() error {
+
+	err := api.HTTP.Start(":" + strconv.Itoa(api.Config.API.Port))
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+
+}
+
+// Shutdown stops accepting new connections and waits for in-flight requests until ctx is done
+func (api *API) Shutdown(ctx context.Context) error {
+
+	return api.HTTP.Shutdown(ctx)
 
 }
 
@@ -130,42 +232,215 @@ func (api *API) GetPaginationParams(c echo.Context) (*PaginationParams, error) {
 
 }
 
-// getSupply returns ACME supply
-func (api *API) getSupply(c echo.Context) error {
+// Page returns bounds of the requested page within total items
+func (p *PaginationParams) Page(total int) (int, int) {
+
+	start := p.Start
+	if start > total {
+		start = total
+	}
+
+	end := start + p.Count
+	if end > total {
+		end = total
+	}
+
+	return start, end
+
+}
+
+// GetStakersParams parses stakers filter and sort params
+func GetStakersParams(c echo.Context) (*StakersParams, error) {
+
+	params := &StakersParams{
+		StakingFilter: store.StakingFilter{
+			Type:               c.QueryParam("type"),
+			Status:             c.QueryParam("status"),
+			Delegate:           c.QueryParam("delegate"),
+			AcceptingDelegates: c.QueryParam("acceptingDelegates"),
+			IdentityPrefix:     c.QueryParam("search"),
+		},
+		Sort:  c.QueryParam("sort"),
+		Order: c.QueryParam("order"),
+	}
+
+	for name, dst := range map[string]**schema.Amount{"minBalance": &params.MinBalance, "maxBalance": &params.MaxBalance} {
+		if c.QueryParam(name) == "" {
+			continue
+		}
+		n, err := schema.ParseAmount(c.QueryParam(name))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' expected to be an integer, '%s' received", name, c.QueryParam(name))
+		}
+		*dst = &n
+	}
 
-	res := &SupplyResponse{ACME: *store.ACME}
+	switch params.Sort {
+	case "", "balance", "identity", "type":
+	default:
+		return nil, fmt.Errorf("'sort' expected to be one of balance, identity, type, '%s' received", params.Sort)
+	}
+
+	switch params.Order {
+	case "":
+		// the largest balances go first
+		params.Order = "asc"
+		if params.Sort == "balance" {
+			params.Order = "desc"
+		}
+	case "asc", "desc":
+	default:
+		return nil, fmt.Errorf("'order' expected to be asc or desc, '%s' received", params.Order)
+	}
+
+	return params, nil
 
-	res.Staked = store.GetTotalStake()
-	res.Circulating = res.Total - res.Staked
+}
 
-	res.TotalTokens = math.Round(float64(res.Total) * math.Pow10(-1*int(res.Precision)))
-	res.MaxTokens = math.Round(float64(res.Max) * math.Pow10(-1*int(res.Precision)))
-	res.CirculatingTokens = math.Round(float64(res.Circulating) * math.Pow10(-1*int(res.Precision)))
-	res.StakedTokens = math.Round(float64(res.Staked) * math.Pow10(-1*int(res.Precision)))
+// GetIdentityParam parses identity path param, accepts both "acc://name.acme" (URL-encoded) and "name.acme"
+func GetIdentityParam(c echo.Context) (string, error) {
 
-	res.UpdatedAt = store.UpdatedAt
+	identity, err := url.PathUnescape(c.Param("identity"))
+	if err != nil {
+		err = fmt.Errorf("'identity' expected to be an Accumulate URL, '%s' received", c.Param("identity"))
+		log.Error(err)
+		return "", err
+	}
 
+	identity = strings.TrimSuffix(identity, "/")
+	if identity == "" {
+		return "", fmt.Errorf("'identity' is required")
+	}
+
+	return NormalizeIdentity(identity), nil
+
+}
+
+// NormalizeIdentity adds missing acc:// scheme to identity
+func NormalizeIdentity(identity string) string {
+
+	if !strings.HasPrefix(strings.ToLower(identity), "acc://") {
+		identity = "acc://" + identity
+	}
+
+	return identity
+
+}
+
+// GetSnapshot returns the latest snapshot, or an error if no ingestion cycle has completed yet
+func (api *API) GetSnapshot() (*schema.Snapshot, error) {
+
+	snapshot := api.Store.Snapshot()
+	if snapshot == nil || snapshot.ACME == nil {
+		return nil, fmt.Errorf("metrics are not available yet, try again later")
+	}
+
+	return snapshot, nil
+
+}
+
+// getSupply returns ACME supply
+func (api *API) getSupply(c echo.Context) error {
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	res := api.newACMESupplyResponse(snapshot)
+
+	// plain text values are rounded to whole tokens
 	switch c.Param("filter") {
 	case "total":
-		return c.String(http.StatusOK, fmt.Sprintf("%.f", res.TotalTokens))
+		return c.String(http.StatusOK, res.Total.TokensRounded(res.Precision, 0))
 	case "max":
-		return c.String(http.StatusOK, fmt.Sprintf("%.f", res.MaxTokens))
+		return c.String(http.StatusOK, res.Max.TokensRounded(res.Precision, 0))
 	case "circulating":
-		return c.String(http.StatusOK, fmt.Sprintf("%.f", res.CirculatingTokens))
+		return c.String(http.StatusOK, res.Circulating.TokensRounded(res.Precision, 0))
 	case "staked":
-		return c.String(http.StatusOK, fmt.Sprintf("%.f", res.StakedTokens))
+		return c.String(http.StatusOK, res.Staked.TokensRounded(res.Precision, 0))
+	case "locked":
+		return c.String(http.StatusOK, res.Locked.TokensRounded(res.Precision, 0))
 	}
 
 	return c.JSON(http.StatusOK, res)
 
 }
 
+// ACMESupplyMethodology explains circulating supply of ACME
+const ACMESupplyMethodology = "circulating = total - staked - locked. Total is ACME issued so far. " +
+	"Staked is the sum of balances of staking accounts registered in the staking data account. " +
+	"Locked is the sum of balances of configured treasury, foundation, vesting and other locked accounts, " +
+	"accounts that are also staking accounts are counted as staked only. Balances are fetched every ingestion cycle."
+
+// TokenSupplyMethodology explains circulating supply of tokens other than ACME
+const TokenSupplyMethodology = "circulating = total. Total is tokens issued so far, only ACME can be staked or locked."
+
+// newACMESupplyResponse returns ACME supply, staked and locked ACME is not circulating
+func (api *API) newACMESupplyResponse(snapshot *schema.Snapshot) *SupplyResponse {
+
+	token := &schema.Token{
+		URL:       api.Config.ACME.TokenIssuer,
+		Symbol:    snapshot.ACME.Symbol,
+		Precision: snapshot.ACME.Precision,
+		Total:     snapshot.ACME.Total,
+		Max:       snapshot.ACME.Max,
+	}
+
+	res := newSupplyResponse(snapshot, token, store.GetTotalStake(snapshot.StakingRecords))
+
+	res.Locked = store.GetLockedSupply(snapshot.LockedAccounts)
+	res.Circulating = store.GetCirculatingSupply(snapshot)
+	res.LockedTokens = res.Locked.Tokens(res.Precision)
+	res.CirculatingTokens = res.Circulating.Tokens(res.Precision)
+	res.Methodology = ACMESupplyMethodology
+
+	res.LockedAccounts = []*LockedAccountResponse{}
+	for _, a := range snapshot.LockedAccounts {
+		res.LockedAccounts = append(res.LockedAccounts, &LockedAccountResponse{LockedAccount: a, BalanceTokens: a.Balance.Tokens(res.Precision)})
+	}
+
+	return res
+
+}
+
+// newSupplyResponse returns supply of token with staked amount
+func newSupplyResponse(snapshot *schema.Snapshot, token *schema.Token, staked schema.Amount) *SupplyResponse {
+
+	res := &SupplyResponse{URL: token.URL, SnapshotID: snapshot.ID}
+
+	res.Symbol = token.Symbol
+	res.Precision = token.Precision
+	res.Total = token.Total
+	res.Max = token.Max
+
+	res.Staked = staked
+	res.Circulating = res.Total.Sub(res.Staked)
+
+	res.TotalTokens = res.Total.Tokens(res.Precision)
+	res.MaxTokens = res.Max.Tokens(res.Precision)
+	res.CirculatingTokens = res.Circulating.Tokens(res.Precision)
+	res.StakedTokens = res.Staked.Tokens(res.Precision)
+	res.LockedTokens = res.Locked.Tokens(res.Precision)
+	res.Methodology = TokenSupplyMethodology
+
+	res.UpdatedAt = &snapshot.UpdatedAt
+
+	return res
+
+}
+
 // getStaking returns staking metrics
 func (api *API) getStaking(c echo.Context) error {
 
-	validators := store.GetValidatorsNumber()
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
 
-	res := &StakingResponse{ValidatorsNumber: *validators}
+	validators := store.GetValidatorsNumber(snapshot.StakingRecords)
+
+	res := &StakingResponse{ValidatorsNumber: *validators, SnapshotID: snapshot.ID}
 
 	return c.JSON(http.StatusOK, res)
 
@@ -179,12 +454,106 @@ func (api *API) getStakers(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadGateway, Error: err.Error()})
 	}
 
-	res := &StakersResponse{}
-	res.Result = store.StakingRecords.Items[params.Start : params.Start+params.Count]
+	stakersParams, err := GetStakersParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	// filtered records are a new slice, so sorting keeps the snapshot untouched
+	records := store.FilterStakingRecords(snapshot.StakingRecords, &stakersParams.StakingFilter)
+
+	if stakersParams.Sort != "" {
+		store.SortStakingRecords(records, stakersParams.Sort, stakersParams.Order == "desc")
+	}
+
+	start, end := params.Page(len(records))
+
+	res := &StakersResponse{SnapshotID: snapshot.ID}
+	res.Result = records[start:end]
 	res.Start = params.Start
 	res.Count = params.Count
-	res.Total = len(store.StakingRecords.Items)
+	res.Total = len(records)
 
 	return c.JSON(http.StatusOK, res)
 
 }
+
+// getStaker returns staker with its balance, rank and share of total stake
+func (api *API) getStaker(c echo.Context) error {
+
+	identity, err := GetIdentityParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	record := store.SearchStakingRecordByIdentity(snapshot.StakingRecords, identity)
+	if record == nil {
+		return c.JSON(http.StatusNotFound, &ErrorResponse{Code: http.StatusNotFound, Error: fmt.Sprintf("staker '%s' not found", identity)})
+	}
+
+	res := &StakerResponse{StakingRecord: record, SnapshotID: snapshot.ID}
+
+	res.BalanceTokens = record.Balance.Tokens(snapshot.ACME.Precision)
+	res.Rank = store.GetStakerRank(snapshot.StakingRecords, record)
+
+	res.Share = record.Balance.Ratio(store.GetTotalStake(snapshot.StakingRecords))
+
+	if record.Type == "delegated" && record.Delegate != "" {
+		res.Validator = store.SearchStakingRecordByIdentity(snapshot.StakingRecords, NormalizeIdentity(record.Delegate))
+	}
+
+	res.Yield = api.getStakerYield(record)
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getStakerHistory returns every version of staker's record
+func (api *API) getStakerHistory(c echo.Context) error {
+
+	identity, err := GetIdentityParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	history := api.Store.StakingHistory(identity)
+	if len(history) == 0 {
+		return c.JSON(http.StatusNotFound, &ErrorResponse{Code: http.StatusNotFound, Error: fmt.Sprintf("staker '%s' not found", identity)})
+	}
+
+	res := &StakerHistoryResponse{Identity: history[len(history)-1].Identity, Result: history}
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getIngestion returns stats of the latest ingestion cycle
+func (api *API) getIngestion(c echo.Context) error {
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	res := &IngestionResponse{CycleStats: snapshot.Stats, EndpointsStatus: api.Client.EndpointsStatus(), SnapshotID: snapshot.ID, UpdatedAt: snapshot.UpdatedAt}
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getConfig returns runtime config of the running instance
+func (api *API) getConfig(c echo.Context) error {
+
+	return c.JSON(http.StatusOK, api.Config)
+
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/echo/v4"
+)
+
+type SupplyFlowsResponse struct {
+	Symbol    string    `json:"symbol"`
+	Precision int64     `json:"precision"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Interval  string    `json:"interval"`
+	// Issued, Burned, NetChange and Burns sum flows of the range
+	Issued          schema.Amount `json:"issued"`
+	Burned          schema.Amount `json:"burned"`
+	NetChange       schema.Amount `json:"netChange"`
+	IssuedTokens    string        `json:"issuedTokens"`
+	BurnedTokens    string        `json:"burnedTokens"`
+	NetChangeTokens string        `json:"netChangeTokens"`
+	Burns           int64         `json:"burns"`
+	// Result lists flows of days, or weeks, within [from, to] with any tracked cycle
+	Result     []*schema.SupplyFlow `json:"result"`
+	SnapshotID int64                `json:"snapshotId"`
+}
+
+// getSupplyFlows returns ACME issued, burned and net change of supply, summed by interval
+func (api *API) getSupplyFlows(c echo.Context) error {
+
+	params, err := GetHistoryParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	// flows are stored by day
+	if HistoryIntervals[params.Interval] < 24*time.Hour {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: fmt.Sprintf("'interval' expected to be one of day, week, '%s' received", params.Interval)})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	flows := api.Store.SupplyFlows(params.From, params.To)
+
+	res := &SupplyFlowsResponse{
+		Symbol:     snapshot.ACME.Symbol,
+		Precision:  snapshot.ACME.Precision,
+		From:       params.From,
+		To:         params.To,
+		Interval:   params.Interval,
+		Result:     store.GroupSupplyFlows(flows, HistoryIntervals[params.Interval]),
+		SnapshotID: snapshot.ID,
+	}
+
+	for _, f := range flows {
+		res.Issued = res.Issued.Add(f.Issued)
+		res.Burned = res.Burned.Add(f.Burned)
+		res.NetChange = res.NetChange.Add(f.NetChange)
+		res.Burns += f.Burns
+	}
+
+	res.IssuedTokens = res.Issued.Tokens(res.Precision)
+	res.BurnedTokens = res.Burned.Tokens(res.Precision)
+	res.NetChangeTokens = res.NetChange.Tokens(res.Precision)
+
+	return c.JSON(http.StatusOK, res)
+
+}
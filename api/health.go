@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/labstack/echo/v4"
+)
+
+const HealthStatusOK = "ok"
+const HealthStatusReady = "ready"
+const HealthStatusNotReady = "not ready"
+
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
+
+type ReadinessResponse struct {
+	Status     string             `json:"status"`
+	Reason     string             `json:"reason,omitempty"`
+	SnapshotID int64              `json:"snapshotId"`
+	UpdatedAt  *time.Time         `json:"updatedAt"`
+	Age        float64            `json:"age"`
+	StaleAfter float64            `json:"staleAfter"`
+	LastCycle  *schema.CycleStats `json:"lastCycle"`
+}
+
+// getLiveness reports that the process is up and serving requests
+func (api *API) getLiveness(c echo.Context) error {
+
+	return c.JSON(http.StatusOK, &LivenessResponse{Status: HealthStatusOK})
+
+}
+
+// getReadiness reports whether served metrics are available and fresh
+func (api *API) getReadiness(c echo.Context) error {
+
+	res := &ReadinessResponse{Status: HealthStatusNotReady, StaleAfter: time.Duration(api.Config.Ingest.StaleAfter).Seconds()}
+
+	snapshot := api.Store.Snapshot()
+	if snapshot == nil {
+		res.Reason = "no ingestion cycle has completed yet"
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	res.SnapshotID = snapshot.ID
+	res.LastCycle = snapshot.Stats
+
+	if snapshot.ACME == nil || snapshot.UpdatedAt.IsZero() {
+		res.Reason = "no ingestion cycle has succeeded yet"
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	age := time.Since(snapshot.UpdatedAt)
+
+	res.UpdatedAt = &snapshot.UpdatedAt
+	res.Age = age.Seconds()
+
+	if age > time.Duration(api.Config.Ingest.StaleAfter) {
+		res.Reason = "metrics are stale, last successful ingestion cycle is older than " + api.Config.Ingest.StaleAfter.String()
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	res.Status = HealthStatusReady
+
+	return c.JSON(http.StatusOK, res)
+
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/echo/v4"
+)
+
+const DefaultHistoryInterval = "day"
+
+// DefaultHistoryRange is used when 'from' is not set
+const DefaultHistoryRange = 30 * 24 * time.Hour
+
+// HistoryIntervals lists supported downsampling intervals
+var HistoryIntervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+type HistoryParams struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+type SupplyHistoryResponse struct {
+	Symbol    string                 `json:"symbol"`
+	Precision int64                  `json:"precision"`
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Interval  string                 `json:"interval"`
+	Result    []*schema.SupplyCandle `json:"result"`
+}
+
+// GetHistoryParams parses time range and interval, 'from' and 'to' accept RFC 3339 time or unix timestamp
+func GetHistoryParams(c echo.Context) (*HistoryParams, error) {
+
+	params := &HistoryParams{To: time.Now().UTC(), Interval: DefaultHistoryInterval}
+
+	if c.QueryParam("to") != "" {
+		to, err := parseTime(c.QueryParam("to"))
+		if err != nil {
+			return nil, fmt.Errorf("'to' expected to be RFC 3339 time or unix timestamp, '%s' received", c.QueryParam("to"))
+		}
+		params.To = to
+	}
+
+	params.From = params.To.Add(-DefaultHistoryRange)
+
+	if c.QueryParam("from") != "" {
+		from, err := parseTime(c.QueryParam("from"))
+		if err != nil {
+			return nil, fmt.Errorf("'from' expected to be RFC 3339 time or unix timestamp, '%s' received", c.QueryParam("from"))
+		}
+		params.From = from
+	}
+
+	if params.From.After(params.To) {
+		return nil, fmt.Errorf("'from' must not be after 'to'")
+	}
+
+	if c.QueryParam("interval") != "" {
+		params.Interval = c.QueryParam("interval")
+	}
+
+	if _, ok := HistoryIntervals[params.Interval]; !ok {
+		return nil, fmt.Errorf("'interval' expected to be one of hour, day, week, '%s' received", params.Interval)
+	}
+
+	return params, nil
+
+}
+
+// parseTime parses RFC 3339 time or unix timestamp in seconds
+func parseTime(raw string) (time.Time, error) {
+
+	if ts, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(ts, 0).UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, raw)
+
+}
+
+// getSupplyHistory returns ACME supply series downsampled by interval
+func (api *API) getSupplyHistory(c echo.Context) error {
+
+	params, err := GetHistoryParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	points := api.Store.SupplyHistory(params.From, params.To)
+
+	res := &SupplyHistoryResponse{
+		Symbol:    snapshot.ACME.Symbol,
+		Precision: snapshot.ACME.Precision,
+		From:      params.From,
+		To:        params.To,
+		Interval:  params.Interval,
+		Result:    store.DownsampleSupply(points, HistoryIntervals[params.Interval]),
+	}
+
+	return c.JSON(http.StatusOK, res)
+
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler serves Prometheus metrics
+func MetricsHandler() echo.HandlerFunc {
+
+	return echo.WrapHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+
+}
+
+// MetricsMiddleware observes duration of HTTP requests by route
+func MetricsMiddleware() echo.MiddlewareFunc {
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+
+			start := time.Now()
+
+			err := next(c)
+			if err != nil {
+				// let echo write error response, so its status is observed
+				c.Error(err)
+			}
+
+			// requests not matching any route would blow up label cardinality
+			route := c.Path()
+			if route == "" || err == echo.ErrNotFound || err == echo.ErrMethodNotAllowed {
+				route = "unmatched"
+			}
+
+			metrics.HTTPRequestDuration.WithLabelValues(route, c.Request().Method, strconv.Itoa(c.Response().Status)).Observe(time.Since(start).Seconds())
+
+			return nil
+
+		}
+	}
+
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/echo/v4"
+)
+
+type RewardsResponse struct {
+	Symbol    string `json:"symbol"`
+	Precision int64  `json:"precision"`
+	// Total and Payouts cover every payout recorded so far
+	Total       schema.Amount `json:"total"`
+	TotalTokens string        `json:"totalTokens"`
+	Payouts     int           `json:"payouts"`
+	// Backfilled and BackfilledPayouts cover payouts of Total found by the first scan of accounts, paid at unknown time
+	Backfilled        schema.Amount `json:"backfilled"`
+	BackfilledPayouts int           `json:"backfilledPayouts"`
+	From              time.Time     `json:"from"`
+	To                time.Time     `json:"to"`
+	Interval          string        `json:"interval"`
+	// Periods sum payouts first seen within [from, to], backfilled payouts are left out
+	Periods    []*schema.RewardsPeriod `json:"periods"`
+	SnapshotID int64                   `json:"snapshotId"`
+}
+
+type StakerRewardsResponse struct {
+	Identity string `json:"identity"`
+	RewardsResponse
+	// Result lists a page of the staker's payouts, the latest first, Payouts is the number of all payouts
+	Result []*schema.Reward `json:"result"`
+	PaginationParams
+}
+
+// getRewards returns staking rewards paid to all stakers
+func (api *API) getRewards(c echo.Context) error {
+
+	params, err := GetHistoryParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	all := api.Store.Rewards(time.Time{}, time.Now())
+
+	res := newRewardsResponse(snapshot, params, all)
+	res.Periods = store.GroupRewards(api.Store.Rewards(params.From, params.To), HistoryIntervals[params.Interval])
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getStakerRewards returns staking rewards paid to staker
+func (api *API) getStakerRewards(c echo.Context) error {
+
+	identity, err := GetIdentityParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	pagination, err := api.GetPaginationParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	params, err := GetHistoryParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	rewards := api.Store.StakerRewards(identity)
+
+	record := store.SearchStakingRecordByIdentity(snapshot.StakingRecords, identity)
+	if record == nil && len(rewards) == 0 {
+		return c.JSON(http.StatusNotFound, &ErrorResponse{Code: http.StatusNotFound, Error: fmt.Sprintf("staker '%s' not found", identity)})
+	}
+
+	res := &StakerRewardsResponse{Identity: identity, RewardsResponse: *newRewardsResponse(snapshot, params, rewards)}
+	if record != nil {
+		res.Identity = record.Identity
+	}
+
+	inRange := []*schema.Reward{}
+	for _, r := range rewards {
+		if !r.FirstSeen.Before(params.From) && !r.FirstSeen.After(params.To) {
+			inRange = append(inRange, r)
+		}
+	}
+	res.Periods = store.GroupRewards(inRange, HistoryIntervals[params.Interval])
+
+	start, end := pagination.Page(len(rewards))
+
+	// rewards are stored in time order, the latest go first
+	res.Result = []*schema.Reward{}
+	for n := len(rewards) - 1 - start; n > len(rewards)-1-end; n-- {
+		res.Result = append(res.Result, rewards[n])
+	}
+	res.Start = pagination.Start
+	res.Count = pagination.Count
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// newRewardsResponse sums rewards without periods
+func newRewardsResponse(snapshot *schema.Snapshot, params *HistoryParams, rewards []*schema.Reward) *RewardsResponse {
+
+	res := &RewardsResponse{
+		Symbol:     snapshot.ACME.Symbol,
+		Precision:  snapshot.ACME.Precision,
+		Total:      store.GetTotalRewards(rewards),
+		Payouts:    len(rewards),
+		From:       params.From,
+		To:         params.To,
+		Interval:   params.Interval,
+		SnapshotID: snapshot.ID,
+	}
+
+	res.TotalTokens = res.Total.Tokens(res.Precision)
+
+	for _, r := range rewards {
+		if r.Backfill {
+			res.Backfilled = res.Backfilled.Add(r.Amount)
+			res.BackfilledPayouts++
+		}
+	}
+
+	return res
+
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/labstack/echo/v4"
+)
+
+type TokensResponse struct {
+	Result     []*SupplyResponse `json:"result"`
+	SnapshotID int64             `json:"snapshotId"`
+}
+
+// GetTokenParam parses token issuer path param, accepts both "name" and URL-encoded "acc://name"
+func GetTokenParam(c echo.Context) (string, error) {
+
+	issuer, err := url.PathUnescape(c.Param("url"))
+	if err != nil || issuer == "" {
+		return "", fmt.Errorf("'url' expected to be a token issuer URL, '%s' received", c.Param("url"))
+	}
+
+	return NormalizeIdentity(strings.TrimSuffix(issuer, "/")), nil
+
+}
+
+// getTokens returns supply of every tracked token, ACME first
+func (api *API) getTokens(c echo.Context) error {
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	res := &TokensResponse{Result: []*SupplyResponse{}, SnapshotID: snapshot.ID}
+
+	for _, token := range snapshot.Tokens {
+		res.Result = append(res.Result, api.newTokenSupplyResponse(snapshot, token))
+	}
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getTokenSupply returns supply of tracked token
+func (api *API) getTokenSupply(c echo.Context) error {
+
+	issuer, err := GetTokenParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	for _, token := range snapshot.Tokens {
+		if strings.EqualFold(NormalizeIdentity(token.URL), issuer) {
+			return c.JSON(http.StatusOK, api.newTokenSupplyResponse(snapshot, token))
+		}
+	}
+
+	return c.JSON(http.StatusNotFound, &ErrorResponse{Code: http.StatusNotFound, Error: fmt.Sprintf("token '%s' is not tracked", issuer)})
+
+}
+
+// newTokenSupplyResponse returns supply of token, only ACME can be staked
+func (api *API) newTokenSupplyResponse(snapshot *schema.Snapshot, token *schema.Token) *SupplyResponse {
+
+	if strings.EqualFold(NormalizeIdentity(token.URL), NormalizeIdentity(api.Config.ACME.TokenIssuer)) {
+		return api.newACMESupplyResponse(snapshot)
+	}
+
+	return newSupplyResponse(snapshot, token, schema.Amount{})
+
+}
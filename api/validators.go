@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/echo/v4"
+)
+
+type ValidatorsResponse struct {
+	Result []*schema.Validator `json:"result"`
+	PaginationResponse
+	SnapshotID int64 `json:"snapshotId"`
+}
+
+type DelegatorsResponse struct {
+	Validator *schema.Validator       `json:"validator"`
+	Result    []*schema.StakingRecord `json:"result"`
+	PaginationResponse
+	SnapshotID int64 `json:"snapshotId"`
+}
+
+// getValidators returns validators with stake delegated to them
+func (api *API) getValidators(c echo.Context) error {
+
+	params, err := api.GetPaginationParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	validators := store.GetValidators(snapshot.StakingRecords)
+
+	start, end := params.Page(len(validators))
+
+	res := &ValidatorsResponse{SnapshotID: snapshot.ID}
+	res.Result = validators[start:end]
+	res.Start = params.Start
+	res.Count = params.Count
+	res.Total = len(validators)
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getDelegators returns stakers delegating to validator, ordered by balance
+func (api *API) getDelegators(c echo.Context) error {
+
+	identity, err := GetIdentityParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	params, err := api.GetPaginationParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &ErrorResponse{Code: http.StatusBadRequest, Error: err.Error()})
+	}
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	var validator *schema.Validator
+	for _, v := range store.GetValidators(snapshot.StakingRecords) {
+		if strings.EqualFold(v.Identity, identity) {
+			validator = v
+			break
+		}
+	}
+
+	if validator == nil {
+		return c.JSON(http.StatusNotFound, &ErrorResponse{Code: http.StatusNotFound, Error: fmt.Sprintf("validator '%s' not found", identity)})
+	}
+
+	delegators := store.FilterStakingRecords(snapshot.StakingRecords, &store.StakingFilter{Type: "delegated", Delegate: validator.Identity})
+	store.SortStakingRecords(delegators, "balance", true)
+
+	start, end := params.Page(len(delegators))
+
+	res := &DelegatorsResponse{Validator: validator, SnapshotID: snapshot.ID}
+	res.Result = delegators[start:end]
+	res.Start = params.Start
+	res.Count = params.Count
+	res.Total = len(delegators)
+
+	return c.JSON(http.StatusOK, res)
+
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/echo/v4"
+)
+
+// YieldWindows lists trailing windows of realised yield in days
+var YieldWindows = []int{7, 30, 90}
+
+type YieldResponse struct {
+	// Since is the first successful ingestion cycle, payouts are tracked from it
+	Since      *time.Time                 `json:"since"`
+	Network    []*schema.Yield            `json:"network"`
+	ByType     map[string][]*schema.Yield `json:"byType"`
+	SnapshotID int64                      `json:"snapshotId"`
+}
+
+// getYield returns realised APR and APY of staking over trailing windows, network-wide and by staking type
+func (api *API) getYield(c echo.Context) error {
+
+	snapshot, err := api.GetSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, &ErrorResponse{Code: http.StatusServiceUnavailable, Error: err.Error()})
+	}
+
+	now := time.Now()
+	since := api.trackingSince(now)
+
+	rewards := api.Store.Rewards(now.Add(-time.Duration(YieldWindows[len(YieldWindows)-1])*24*time.Hour), now)
+
+	types := make(map[string]string, len(snapshot.StakingRecords))
+	for _, r := range snapshot.StakingRecords {
+		types[strings.ToLower(r.Identity)] = store.GetStakingType(r)
+	}
+
+	rewardsByType := make(map[string][]*schema.Reward)
+	for _, r := range rewards {
+		if t, ok := types[strings.ToLower(r.Identity)]; ok {
+			rewardsByType[t] = append(rewardsByType[t], r)
+		}
+	}
+
+	res := &YieldResponse{Network: []*schema.Yield{}, ByType: make(map[string][]*schema.Yield), SnapshotID: snapshot.ID}
+	if !since.Equal(now) {
+		res.Since = &since
+	}
+
+	total := store.GetTotalStake(snapshot.StakingRecords)
+	byType := store.GetStakeByType(snapshot.StakingRecords)
+
+	for t := range byType {
+		res.ByType[t] = []*schema.Yield{}
+	}
+
+	// windows not covered by tracking yet are omitted
+	for _, days := range YieldWindows {
+		if y := store.GetYield(rewards, total, days, since, now); y != nil {
+			res.Network = append(res.Network, y)
+		}
+		for t, stake := range byType {
+			if y := store.GetYield(rewardsByType[t], stake, days, since, now); y != nil {
+				res.ByType[t] = append(res.ByType[t], y)
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+// getStakerYield returns realised yield of staker over trailing windows covered by tracking
+func (api *API) getStakerYield(record *schema.StakingRecord) []*schema.Yield {
+
+	now := time.Now()
+	since := api.trackingSince(now)
+	rewards := api.Store.StakerRewards(record.Identity)
+
+	res := []*schema.Yield{}
+	for _, days := range YieldWindows {
+		if y := store.GetYield(rewards, record.Balance, days, since, now); y != nil {
+			res = append(res, y)
+		}
+	}
+
+	return res
+
+}
+
+// trackingSince returns time of the first recorded supply point, which is the first successful ingestion cycle,
+// or now if there is none
+func (api *API) trackingSince(now time.Time) time.Time {
+
+	points := api.Store.SupplyHistory(time.Time{}, now)
+	if len(points) == 0 {
+		return now
+	}
+
+	return points[0].Time
+
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"time"
+)
+
+// EnvPrefix is prepended to every environment variable read by the config loader
+const EnvPrefix = "METRICS_"
+
+type Config struct {
+	Accumulate  Accumulate  `json:"accumulate" yaml:"accumulate"`
+	API         API         `json:"api" yaml:"api"`
+	Aggregators Aggregators `json:"aggregators" yaml:"aggregators"`
+	ACME        ACME        `json:"acme" yaml:"acme"`
+	Tokens      Tokens      `json:"tokens" yaml:"tokens"`
+	Supply      Supply      `json:"supply" yaml:"supply"`
+	Staking     Staking     `json:"staking" yaml:"staking"`
+	Ingest      Ingest      `json:"ingest" yaml:"ingest"`
+	Store       Store       `json:"store" yaml:"store"`
+}
+
+type Accumulate struct {
+	Endpoints           []string       `json:"endpoints" yaml:"endpoints" env:"ACCUMULATE_ENDPOINTS" usage:"Comma-separated Accumulate API endpoints, the healthiest one serves calls" validate:"min=1,dive,url"`
+	APIVersion          string         `json:"apiVersion" yaml:"apiVersion" env:"ACCUMULATE_API_VERSION" usage:"Accumulate API version served by endpoints: v2 or v3" validate:"oneof=v2 v3"`
+	HealthCheckInterval Duration       `json:"healthCheckInterval" yaml:"healthCheckInterval" env:"ACCUMULATE_HEALTH_CHECK_INTERVAL" usage:"Interval of endpoints health checks" validate:"gt=0"`
+	Timeout             Duration       `json:"timeout" yaml:"timeout" env:"ACCUMULATE_TIMEOUT" usage:"Accumulate API client timeout" validate:"gt=0"`
+	RateLimit           float64        `json:"rateLimit" yaml:"rateLimit" env:"ACCUMULATE_RATE_LIMIT" usage:"Max outbound requests per second, 0 means unlimited" validate:"min=0"`
+	Retry               Retry          `json:"retry" yaml:"retry"`
+	CircuitBreaker      CircuitBreaker `json:"circuitBreaker" yaml:"circuitBreaker"`
+}
+
+type Retry struct {
+	MaxAttempts    int      `json:"maxAttempts" yaml:"maxAttempts" env:"ACCUMULATE_RETRY_MAX_ATTEMPTS" usage:"Max attempts of a failing call, 1 disables retries" validate:"min=1"`
+	InitialBackoff Duration `json:"initialBackoff" yaml:"initialBackoff" env:"ACCUMULATE_RETRY_INITIAL_BACKOFF" usage:"Delay before the first retry" validate:"gt=0"`
+	MaxBackoff     Duration `json:"maxBackoff" yaml:"maxBackoff" env:"ACCUMULATE_RETRY_MAX_BACKOFF" usage:"Max delay between retries" validate:"gtefield=InitialBackoff"`
+	Multiplier     float64  `json:"multiplier" yaml:"multiplier" env:"ACCUMULATE_RETRY_MULTIPLIER" usage:"Backoff growth factor" validate:"min=1"`
+	Jitter         float64  `json:"jitter" yaml:"jitter" env:"ACCUMULATE_RETRY_JITTER" usage:"Random fraction (0..1) subtracted from every backoff" validate:"min=0,max=1"`
+}
+
+type CircuitBreaker struct {
+	FailureThreshold int      `json:"failureThreshold" yaml:"failureThreshold" env:"ACCUMULATE_BREAKER_FAILURES" usage:"Consecutive failures that open the circuit breaker, 0 disables it" validate:"min=0"`
+	Cooldown         Duration `json:"cooldown" yaml:"cooldown" env:"ACCUMULATE_BREAKER_COOLDOWN" usage:"Time the circuit breaker stays open before a trial call" validate:"gt=0"`
+}
+
+type API struct {
+	Port            int      `json:"port" yaml:"port" env:"API_PORT" usage:"REST API port" validate:"min=1,max=65535"`
+	ShutdownTimeout Duration `json:"shutdownTimeout" yaml:"shutdownTimeout" env:"API_SHUTDOWN_TIMEOUT" usage:"Time to drain in-flight requests and stop ingestion on shutdown" validate:"gt=0"`
+}
+
+type Aggregators struct {
+	CoinGeckoDecimals     int `json:"coingeckoDecimals" yaml:"coingeckoDecimals" env:"AGGREGATORS_COINGECKO_DECIMALS" usage:"Decimals of supply returned to CoinGecko" validate:"min=0,max=18"`
+	CoinMarketCapDecimals int `json:"coinmarketcapDecimals" yaml:"coinmarketcapDecimals" env:"AGGREGATORS_COINMARKETCAP_DECIMALS" usage:"Decimals of supply returned to CoinMarketCap" validate:"min=0,max=18"`
+}
+
+type ACME struct {
+	TokenIssuer string `json:"tokenIssuer" yaml:"tokenIssuer" env:"ACME_TOKEN_ISSUER" usage:"ACME token issuer URL" validate:"required,startswith=acc://"`
+}
+
+type Tokens struct {
+	Issuers []string `json:"issuers" yaml:"issuers" env:"TOKENS_ISSUERS" usage:"Comma-separated token issuer URLs to track supply of, ACME is always tracked" validate:"dive,startswith=acc://"`
+}
+
+// Supply is set in config file only, lists of structs can not be set by environment variables or flags
+type Supply struct {
+	LockedAccounts []LockedAccount `json:"lockedAccounts" yaml:"lockedAccounts" validate:"dive"`
+}
+
+// LockedAccount is ACME token account excluded from circulating supply, e.g. treasury or vesting account
+type LockedAccount struct {
+	Label string `json:"label" yaml:"label" validate:"required"`
+	URL   string `json:"url" yaml:"url" validate:"required,startswith=acc://"`
+}
+
+type Staking struct {
+	DataAccount     string `json:"dataAccount" yaml:"dataAccount" env:"STAKING_DATA_ACCOUNT" usage:"Staking registry data account URL" validate:"required,startswith=acc://"`
+	PageSize        int64  `json:"pageSize" yaml:"pageSize" env:"STAKING_PAGESIZE" usage:"Number of data entries requested per page" validate:"min=1"`
+	PayoutAccount   string `json:"payoutAccount" yaml:"payoutAccount" env:"STAKING_PAYOUT_ACCOUNT" usage:"ACME token account paying staking rewards" validate:"required,startswith=acc://"`
+	HistoryPageSize int64  `json:"historyPageSize" yaml:"historyPageSize" env:"STAKING_HISTORY_PAGESIZE" usage:"Number of transactions requested per page of rewards accounts and ACME issuer history" validate:"min=1"`
+}
+
+type Ingest struct {
+	Interval    Duration `json:"interval" yaml:"interval" env:"INGEST_INTERVAL" usage:"Delay between ingestion cycles" validate:"gt=0"`
+	Concurrency int      `json:"concurrency" yaml:"concurrency" env:"INGEST_CONCURRENCY" usage:"Number of concurrent balance requests" validate:"min=1"`
+	StaleAfter  Duration `json:"staleAfter" yaml:"staleAfter" env:"INGEST_STALE_AFTER" usage:"Age of the last successful cycle after which the service is not ready" validate:"gtfield=Interval"`
+}
+
+type Store struct {
+	Driver string `json:"driver" yaml:"driver" env:"STORE_DRIVER" usage:"Storage backend: memory or bolt" validate:"oneof=memory bolt"`
+	Path   string `json:"path" yaml:"path" env:"STORE_PATH" usage:"Database file of the bolt storage backend" validate:"required_if=Driver bolt"`
+}
+
+// Default returns config with default values
+func Default() *Config {
+
+	return &Config{
+		Accumulate: Accumulate{
+			Endpoints:           []string{"https://mainnet.accumulatenetwork.io/v2"},
+			APIVersion:          "v2",
+			HealthCheckInterval: Duration(30 * time.Second),
+			Timeout:             Duration(5 * time.Second),
+			RateLimit:           20,
+			Retry: Retry{
+				MaxAttempts:    4,
+				InitialBackoff: Duration(500 * time.Millisecond),
+				MaxBackoff:     Duration(10 * time.Second),
+				Multiplier:     2,
+				Jitter:         0.5,
+			},
+			CircuitBreaker: CircuitBreaker{
+				FailureThreshold: 10,
+				Cooldown:         Duration(30 * time.Second),
+			},
+		},
+		API: API{
+			Port:            8082,
+			ShutdownTimeout: Duration(15 * time.Second),
+		},
+		Aggregators: Aggregators{
+			CoinGeckoDecimals:     8,
+			CoinMarketCapDecimals: 8,
+		},
+		ACME: ACME{
+			TokenIssuer: "acc://acme",
+		},
+		Tokens: Tokens{
+			Issuers: []string{"acc://acme"},
+		},
+		Staking: Staking{
+			DataAccount:     "acc://staking.acme/registered",
+			PageSize:        10000,
+			PayoutAccount:   "acc://staking.acme/payout",
+			HistoryPageSize: 100,
+		},
+		Ingest: Ingest{
+			Interval:    Duration(10 * time.Minute),
+			Concurrency: 8,
+			StaleAfter:  Duration(30 * time.Minute),
+		},
+		Store: Store{
+			Driver: "memory",
+			Path:   "metrics.db",
+		},
+	}
+
+}
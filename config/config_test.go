@@ -0,0 +1,148 @@
+package config_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/config"
+)
+
+func writeFile(t *testing.T, content string) string {
+
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+
+}
+
+func TestLoadPrecedence(t *testing.T) {
+
+	path := writeFile(t, `
+ingest:
+  interval: 20m
+  concurrency: 4
+staking:
+  pageSize: 500
+`)
+
+	t.Setenv("METRICS_INGEST_INTERVAL", "15m")
+	t.Setenv("METRICS_INGEST_CONCURRENCY", "6")
+
+	cfg, err := config.Load([]string{"-config", path, "-ingest-interval", "12m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Staking.PageSize != 500 {
+		t.Errorf("expected page size 500 from file, got %d", cfg.Staking.PageSize)
+	}
+	if cfg.Ingest.Concurrency != 6 {
+		t.Errorf("expected concurrency 6 from environment over file, got %d", cfg.Ingest.Concurrency)
+	}
+	if time.Duration(cfg.Ingest.Interval) != 12*time.Minute {
+		t.Errorf("expected interval 12m from flag over environment and file, got %s", cfg.Ingest.Interval)
+	}
+	if cfg.Store.Driver != "memory" {
+		t.Errorf("expected default store driver, got %s", cfg.Store.Driver)
+	}
+
+}
+
+func TestLoadServedConfig(t *testing.T) {
+
+	// config served by /v1/config can be loaded back as a config file
+	data, err := json.Marshal(config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"interval":"10m0s"`) {
+		t.Errorf("expected durations serialized as strings, got %s", data)
+	}
+
+	cfg, err := config.Load([]string{"-config", writeFile(t, string(data))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cfg, config.Default()) {
+		t.Errorf("expected default config, got %+v", cfg)
+	}
+
+}
+
+func TestLoadErrors(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		error string
+	}{
+		{
+			name:  "validation",
+			env:   map[string]string{"METRICS_INGEST_CONCURRENCY": "0"},
+			args:  []string{"-store-driver", "sql"},
+			error: "invalid config: ingest.concurrency: 0 must be at least 1; store.driver: 'sql' must be one of: memory bolt",
+		},
+		{
+			name:  "v3 endpoint",
+			args:  []string{"-accumulate-api-version", "v3"},
+			error: "invalid config: accumulate.endpoints[0]: 'https://mainnet.accumulatenetwork.io/v2' is a v2 endpoint, apiVersion v3 requires v3 endpoints",
+		},
+		{
+			name:  "environment",
+			env:   map[string]string{"METRICS_INGEST_INTERVAL": "soon"},
+			error: "invalid environment variable METRICS_INGEST_INTERVAL",
+		},
+		{
+			name:  "integer duration",
+			file:  "ingest:\n  interval: 600000000000\n",
+			error: `duration must be a string like "10m"`,
+		},
+		{
+			name:  "unknown field",
+			file:  "ingest:\n  period: 10m\n",
+			error: "field period not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			args := test.args
+			if test.file != "" {
+				args = append([]string{"-config", writeFile(t, test.file)}, args...)
+			}
+
+			_, err := config.Load(args)
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("expected error containing %q, got %v", test.error, err)
+			}
+
+		})
+	}
+
+}
+
+func TestLoadExample(t *testing.T) {
+
+	if _, err := config.Load([]string{"-config", "../config.example.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a config duration, it is written and read as a string like "10m", so served config can be loaded back
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10m\": %s", err)
+	}
+
+	return d.parse(s)
+
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+
+	if value.Kind != yaml.ScalarNode || value.Tag != "!!str" {
+		return fmt.Errorf("line %d: duration must be a string like \"10m\", '%s' received", value.Line, value.Value)
+	}
+
+	return d.parse(value.Value)
+
+}
+
+func (d *Duration) parse(s string) error {
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+
+}
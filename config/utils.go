@@ -0,0 +1,304 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// field is a config value that can be set from environment variables and flags
+type field struct {
+	Path  string
+	Env   string
+	Flag  string
+	Usage string
+	Value reflect.Value
+}
+
+// Load builds config from defaults, config file, environment variables and command-line flags.
+// Every next source overrides the previous one, so flags have the highest priority.
+func Load(args []string) (*Config, error) {
+
+	cfg := Default()
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), "")
+
+	// flags are stored as raw strings and applied after the environment
+	fs := flag.NewFlagSet("metrics-api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "Path to YAML or JSON config file (env "+EnvPrefix+"CONFIG)")
+
+	flags := make(map[string]*string)
+	for _, f := range fields {
+		flags[f.Flag] = fs.String(f.Flag, "", fmt.Sprintf("%s (env %s, default %v)", f.Usage, EnvPrefix+f.Env, f.Value.Interface()))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range fields {
+		raw, ok := os.LookupEnv(EnvPrefix + f.Env)
+		if !ok {
+			continue
+		}
+		if err := setValue(f.Value, raw); err != nil {
+			return nil, fmt.Errorf("invalid environment variable %s: %s", EnvPrefix+f.Env, err)
+		}
+	}
+
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		for _, f := range fields {
+			if f.Flag != fl.Name {
+				continue
+			}
+			if e := setValue(f.Value, *flags[f.Flag]); e != nil {
+				err = fmt.Errorf("invalid flag -%s: %s", f.Flag, e)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+
+}
+
+// Validate checks config values and returns a readable error listing every invalid field
+func (cfg *Config) Validate() error {
+
+	v := validator.New()
+	v.RegisterStructValidation(validateAccumulate, Accumulate{})
+
+	err := v.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	msgs := make([]string, 0, len(verrs))
+	for _, fe := range verrs {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fieldPath(fe.Namespace()), describeTag(fe)))
+	}
+
+	return fmt.Errorf("invalid config: %s", strings.Join(msgs, "; "))
+
+}
+
+// validateAccumulate rejects v2 endpoints, e.g. the default one, when the v3 API is configured
+func validateAccumulate(sl validator.StructLevel) {
+
+	acc := sl.Current().Interface().(Accumulate)
+	if acc.APIVersion != "v3" {
+		return
+	}
+
+	for i, url := range acc.Endpoints {
+		if strings.HasSuffix(strings.TrimRight(url, "/"), "/v2") {
+			name := fmt.Sprintf("Endpoints[%d]", i)
+			sl.ReportError(url, name, name, "v3endpoint", "")
+		}
+	}
+
+}
+
+// loadFile reads YAML or JSON config file into cfg (JSON is a subset of YAML)
+func loadFile(cfg *Config, path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can not read config file: %s", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err = dec.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("can not parse config file %s: %s", path, err)
+	}
+
+	return nil
+
+}
+
+// collectFields walks config struct and returns every field tagged with env
+func collectFields(v reflect.Value, prefix string) []*field {
+
+	var res []*field
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+		path := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			res = append(res, collectFields(v.Field(i), path)...)
+			continue
+		}
+
+		env := sf.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+
+		res = append(res, &field{
+			Path:  path,
+			Env:   env,
+			Flag:  strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+			Usage: sf.Tag.Get("usage"),
+			Value: v.Field(i),
+		})
+
+	}
+
+	return res
+
+}
+
+// setValue parses raw string into config field
+func setValue(v reflect.Value, raw string) error {
+
+	if d, ok := v.Addr().Interface().(*Duration); ok {
+		return d.parse(raw)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported config value type %s", v.Type())
+		}
+		list := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not an integer", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a boolean", raw)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config value type %s", v.Type())
+	}
+
+	return nil
+
+}
+
+// fieldPath converts validator namespace (Config.Staking.PageSize) into config path (staking.pageSize)
+func fieldPath(namespace string) string {
+
+	t := reflect.TypeOf(Config{})
+	parts := strings.Split(namespace, ".")[1:]
+
+	res := make([]string, 0, len(parts))
+	for _, p := range parts {
+
+		// keep slice index, e.g. Endpoints[1]
+		name, index := p, ""
+		if i := strings.Index(p, "["); i >= 0 {
+			name, index = p[:i], p[i:]
+		}
+
+		if t.Kind() != reflect.Struct {
+			res = append(res, p)
+			continue
+		}
+
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			res = append(res, p)
+			continue
+		}
+
+		res = append(res, strings.Split(sf.Tag.Get("yaml"), ",")[0]+index)
+
+		t = sf.Type
+		for t.Kind() == reflect.Slice || t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+	}
+
+	return strings.Join(res, ".")
+
+}
+
+// describeTag explains failed validation rule
+func describeTag(fe validator.FieldError) string {
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		return fmt.Sprintf("is required when %s", fe.Param())
+	case "url":
+		return fmt.Sprintf("'%v' is not a valid URL", fe.Value())
+	case "startswith":
+		return fmt.Sprintf("'%v' must start with %s", fe.Value(), fe.Param())
+	case "min", "gte":
+		return fmt.Sprintf("%v must be at least %s", fe.Value(), fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%v must be at most %s", fe.Value(), fe.Param())
+	case "gt":
+		return fmt.Sprintf("%v must be greater than %s", fe.Value(), fe.Param())
+	case "gtfield":
+		return fmt.Sprintf("%v must be greater than %s", fe.Value(), fe.Param())
+	case "gtefield":
+		return fmt.Sprintf("%v must not be less than %s", fe.Value(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("'%v' must be one of: %s", fe.Value(), fe.Param())
+	case "v3endpoint":
+		return fmt.Sprintf("'%v' is a v2 endpoint, apiVersion v3 requires v3 endpoints", fe.Value())
+	}
+
+	return fmt.Sprintf("failed '%s' validation", fe.Tag())
+
+}
@@ -0,0 +1,1128 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/accumulate/accumulatetest"
+	"github.com/AccumulateNetwork/metrics-api/api"
+	"github.com/AccumulateNetwork/metrics-api/config"
+	"github.com/AccumulateNetwork/metrics-api/ingest"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+)
+
+const stakingAccount = "acc://staking.acme/registered"
+
+// env is a metrics service wired to mock Accumulate node
+type env struct {
+	t      *testing.T
+	node   *accumulatetest.Server
+	cfg    *config.Config
+	client *accumulate.AccumulateClient
+	store  store.Store
+	api    *api.API
+}
+
+func newEnv(t *testing.T) *env {
+
+	node := accumulatetest.NewServer()
+	t.Cleanup(node.Close)
+
+	cfg := config.Default()
+	cfg.Accumulate.Endpoints = []string{node.URL}
+	cfg.Ingest.Interval = config.Duration(time.Hour)
+
+	client := accumulate.NewAccumulateClient(cfg.Accumulate.Endpoints, 200*time.Millisecond)
+	client.Retry = &accumulate.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}
+
+	st := store.NewMemoryStore()
+
+	return &env{t: t, node: node, cfg: cfg, client: client, store: st, api: api.NewAPI(cfg, st, client)}
+
+}
+
+// addStaker registers staker and its stake account with balance
+func (e *env) addStaker(stakingType, identity, balance string) {
+
+	e.addDelegator(stakingType, identity, "", balance)
+
+}
+
+// addDelegator registers staker delegating to validator
+func (e *env) addDelegator(stakingType, identity, delegate, balance string) {
+
+	e.node.AddStakingEntry(stakingAccount, &schema.StakingRecord{
+		Type:     stakingType,
+		Status:   "registered",
+		Identity: identity,
+		Stake:    identity + "/staking",
+		Rewards:  identity + "/rewards",
+		Delegate: delegate,
+	})
+	e.node.AddTokenAccount(identity+"/staking", "acc://ACME", balance)
+
+}
+
+// ingest runs ingestion until the next snapshot is saved
+func (e *env) ingest() *schema.Snapshot {
+
+	e.t.Helper()
+
+	return e.ingestWith(ingest.NewIngestor(e.cfg, e.client, e.store))
+
+}
+
+// ingestWith runs ingestor until the next snapshot is saved
+func (e *env) ingestWith(ingestor *ingest.Ingestor) *schema.Snapshot {
+
+	e.t.Helper()
+
+	prevID := int64(0)
+	if prev := e.store.Snapshot(); prev != nil {
+		prevID = prev.ID
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		ingestor.Run(ctx)
+		close(done)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if snapshot := e.store.Snapshot(); snapshot != nil && snapshot.ID > prevID {
+			return snapshot
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	e.t.Fatal("ingestion cycle did not complete")
+	return nil
+
+}
+
+// get calls API and decodes JSON response into res
+func (e *env) get(path string, res interface{}) int {
+
+	e.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	e.api.HTTP.ServeHTTP(rec, req)
+
+	if res != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), res); err != nil {
+			e.t.Fatalf("GET %s: can not decode response %q: %s", path, rec.Body.String(), err)
+		}
+	}
+
+	return rec.Code
+
+}
+
+func newPopulatedEnv(t *testing.T) *env {
+
+	e := newEnv(t)
+
+	e.node.AddToken("acc://ACME", "ACME", 8, "30000000000000000", "50000000000000000")
+	e.addStaker("coreValidator", "acc://validator.acme", "10000000000000")
+	e.addDelegator("delegated", "acc://delegator.acme", "acc://validator.acme", "5000000000000")
+	e.addStaker("pure", "acc://pure.acme", "2000000000000")
+	e.addStaker("stakingValidator", "acc://staking-validator.acme", "1000000000000")
+
+	return e
+
+}
+
+func TestSupply(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	snapshot := e.ingest()
+
+	res := &api.SupplyResponse{}
+	if code := e.get("/v1/supply", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Symbol != "ACME" || res.Precision != 8 {
+		t.Errorf("unexpected token %s with precision %d", res.Symbol, res.Precision)
+	}
+	if res.Total.String() != "30000000000000000" || res.Max.String() != "50000000000000000" {
+		t.Errorf("unexpected total %s and max %s", res.Total, res.Max)
+	}
+	if res.Staked.String() != "18000000000000" {
+		t.Errorf("expected staked 18000000000000, got %s", res.Staked)
+	}
+	if res.Circulating.Cmp(res.Total.Sub(res.Staked)) != 0 {
+		t.Errorf("expected circulating %s, got %s", res.Total.Sub(res.Staked), res.Circulating)
+	}
+	if res.TotalTokens != "300000000.00000000" || res.StakedTokens != "180000.00000000" || res.CirculatingTokens != "299820000.00000000" {
+		t.Errorf("unexpected tokens: total %s, staked %s, circulating %s", res.TotalTokens, res.StakedTokens, res.CirculatingTokens)
+	}
+	if res.SnapshotID != snapshot.ID {
+		t.Errorf("expected snapshot %d, got %d", snapshot.ID, res.SnapshotID)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/supply/circulating", nil)
+	rec := httptest.NewRecorder()
+	e.api.HTTP.ServeHTTP(rec, req)
+	if rec.Body.String() != "299820000" {
+		t.Errorf("expected plain circulating supply 299820000, got %q", rec.Body.String())
+	}
+
+}
+
+func TestStaking(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.ingest()
+
+	res := &api.StakingResponse{}
+	if code := e.get("/v1/staking", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	expected := schema.ValidatorsNumber{CoreValidator: 1, StakingValidator: 1, Delegated: 1, Pure: 1}
+	if res.ValidatorsNumber != expected {
+		t.Errorf("expected %+v, got %+v", expected, res.ValidatorsNumber)
+	}
+
+}
+
+func TestStakers(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.ingest()
+
+	res := &api.StakersResponse{}
+	if code := e.get("/v1/staking/stakers?start=1&count=2", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Total != 4 || res.Start != 1 || res.Count != 2 {
+		t.Errorf("unexpected pagination: start %d, count %d, total %d", res.Start, res.Count, res.Total)
+	}
+	if len(res.Result) != 2 {
+		t.Fatalf("expected 2 stakers, got %d", len(res.Result))
+	}
+	if res.Result[0].Identity != "acc://delegator.acme" || res.Result[0].Balance.String() != "5000000000000" {
+		t.Errorf("unexpected staker %+v", res.Result[0])
+	}
+	if res.Result[1].Identity != "acc://pure.acme" || res.Result[1].Balance.String() != "2000000000000" {
+		t.Errorf("unexpected staker %+v", res.Result[1])
+	}
+
+	// a page past the end is empty
+	if code := e.get("/v1/staking/stakers?start=10", res); code != http.StatusOK || len(res.Result) != 0 {
+		t.Errorf("expected empty page, got %d stakers (status %d)", len(res.Result), code)
+	}
+
+	if code := e.get("/v1/staking/stakers?start=-1", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative start, got %d", code)
+	}
+
+}
+
+func TestStakersFilter(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.addStaker("pure", "acc://pure-2.acme", "7000000000000")
+	e.ingest()
+
+	identities := func(path string) ([]string, int) {
+		t.Helper()
+		res := &api.StakersResponse{}
+		if code := e.get(path, res); code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, code)
+		}
+		list := []string{}
+		for _, r := range res.Result {
+			list = append(list, r.Identity)
+		}
+		return list, res.Total
+	}
+
+	cases := []struct {
+		path     string
+		expected []string
+		total    int
+	}{
+		{"/v1/staking/stakers?type=pure&sort=balance", []string{"acc://pure-2.acme", "acc://pure.acme"}, 2},
+		{"/v1/staking/stakers?delegate=validator.acme", []string{"acc://delegator.acme"}, 1},
+		{"/v1/staking/stakers?minBalance=2000000000000&maxBalance=7000000000000&sort=identity&order=desc", []string{"acc://pure.acme", "acc://pure-2.acme", "acc://delegator.acme"}, 3},
+		{"/v1/staking/stakers?search=acc://PURE&sort=balance&order=asc", []string{"acc://pure.acme", "acc://pure-2.acme"}, 2},
+		{"/v1/staking/stakers?sort=balance&count=2", []string{"acc://validator.acme", "acc://pure-2.acme"}, 5},
+		{"/v1/staking/stakers?status=unregistered", []string{}, 0},
+	}
+
+	for _, tc := range cases {
+		list, total := identities(tc.path)
+		if strings.Join(list, ",") != strings.Join(tc.expected, ",") || total != tc.total {
+			t.Errorf("GET %s: expected %v of %d, got %v of %d", tc.path, tc.expected, tc.total, list, total)
+		}
+	}
+
+	for _, path := range []string{"/v1/staking/stakers?sort=rank", "/v1/staking/stakers?order=up", "/v1/staking/stakers?minBalance=x"} {
+		if code := e.get(path, nil); code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected 400, got %d", path, code)
+		}
+	}
+
+}
+
+func TestValidators(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.addDelegator("delegated", "acc://delegator-2.acme", "staking-validator.acme", "20000000000000")
+	e.addDelegator("delegated", "acc://delegator-3.acme", "acc://validator.acme", "1000000000000")
+	e.ingest()
+
+	res := &api.ValidatorsResponse{}
+	if code := e.get("/v1/staking/validators", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Total != 2 || len(res.Result) != 2 {
+		t.Fatalf("expected 2 validators, got %d of %d", len(res.Result), res.Total)
+	}
+
+	expected := []schema.Validator{
+		{Identity: "acc://staking-validator.acme", Type: "stakingValidator", Status: "registered", Balance: schema.NewAmount(1000000000000), Delegated: schema.NewAmount(20000000000000), Delegators: 1, TotalStake: schema.NewAmount(21000000000000)},
+		{Identity: "acc://validator.acme", Type: "coreValidator", Status: "registered", Balance: schema.NewAmount(10000000000000), Delegated: schema.NewAmount(6000000000000), Delegators: 2, TotalStake: schema.NewAmount(16000000000000)},
+	}
+	for i, v := range res.Result {
+		// amounts are compared by value through JSON
+		want, _ := json.Marshal(expected[i])
+		got, _ := json.Marshal(v)
+		if string(got) != string(want) {
+			t.Errorf("expected validator %s, got %s", want, got)
+		}
+	}
+
+	delegators := &api.DelegatorsResponse{}
+	if code := e.get("/v1/staking/validators/validator.acme/delegators", delegators); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if delegators.Total != 2 || delegators.Result[0].Identity != "acc://delegator.acme" || delegators.Result[1].Identity != "acc://delegator-3.acme" {
+		t.Errorf("unexpected delegators %+v", delegators.Result)
+	}
+
+	if code := e.get("/v1/staking/validators/pure.acme/delegators", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for non-validator, got %d", code)
+	}
+
+}
+
+func TestStaker(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.ingest()
+
+	res := &api.StakerResponse{}
+	if code := e.get("/v1/staking/stakers/Delegator.acme", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Identity != "acc://delegator.acme" || res.Balance.String() != "5000000000000" || res.BalanceTokens != "50000.00000000" {
+		t.Errorf("unexpected staker %+v", res.StakingRecord)
+	}
+	if res.Rank != 2 {
+		t.Errorf("expected rank 2, got %d", res.Rank)
+	}
+	if res.Share < 0.2777 || res.Share > 0.2778 {
+		t.Errorf("expected share 5/18, got %f", res.Share)
+	}
+	if res.Validator == nil || res.Validator.Identity != "acc://validator.acme" {
+		t.Errorf("expected validator acc://validator.acme, got %+v", res.Validator)
+	}
+
+	res = &api.StakerResponse{}
+	if code := e.get("/v1/staking/stakers/acc:%2F%2Fvalidator.acme", res); code != http.StatusOK || res.Rank != 1 || res.Validator != nil {
+		t.Errorf("unexpected validator %+v (status %d)", res, code)
+	}
+
+	if code := e.get("/v1/staking/stakers/unknown.acme", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown staker, got %d", code)
+	}
+
+}
+
+func TestNotReady(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+
+	if code := e.get("/v1/supply", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before ingestion, got %d", code)
+	}
+
+	e.node.FailURL("acc://ACME", accumulatetest.ErrCodeNotFound, "Not Found")
+	snapshot := e.ingest()
+
+	if snapshot.Stats.Success {
+		t.Error("expected failed cycle without ACME supply")
+	}
+	if code := e.get("/v1/supply", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without ACME supply, got %d", code)
+	}
+
+	e.node.FailURL("acc://ACME", 0, "")
+	e.ingest()
+
+	if code := e.get("/v1/supply", nil); code != http.StatusOK {
+		t.Errorf("expected 200 after recovery, got %d", code)
+	}
+
+}
+
+func TestBalanceFailures(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+
+	e.node.FailURL("acc://pure.acme/staking", accumulatetest.ErrCodeNotFound, "Not Found")
+	e.node.Delay("acc://delegator.acme/staking", time.Second)
+
+	snapshot := e.ingest()
+
+	if !snapshot.Stats.Success {
+		t.Errorf("expected successful cycle, errors: %v", snapshot.Stats.Errors)
+	}
+	if snapshot.Stats.Balances.Succeeded != 2 || snapshot.Stats.Balances.Failed != 2 {
+		t.Errorf("unexpected balance stats %+v", snapshot.Stats.Balances)
+	}
+	if snapshot.Stats.ErrorsTotal != 2 {
+		t.Errorf("expected 2 cycle errors, got %d: %v", snapshot.Stats.ErrorsTotal, snapshot.Stats.Errors)
+	}
+
+	res := &api.SupplyResponse{}
+	e.get("/v1/supply", res)
+	if res.Staked.String() != "11000000000000" {
+		t.Errorf("expected staked 11000000000000 without failed balances, got %s", res.Staked)
+	}
+
+}
+
+func TestIngestionEndpoints(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.ingest()
+
+	res := &api.IngestionResponse{}
+	if code := e.get("/v1/ingestion", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if len(res.EndpointsStatus) != 1 {
+		t.Fatalf("expected status of 1 endpoint, got %d", len(res.EndpointsStatus))
+	}
+	status := res.EndpointsStatus[0]
+	if status.URL != e.node.URL || !status.Healthy || status.Served == 0 || status.LastCheck != nil {
+		t.Errorf("expected healthy endpoint serving calls and not checked yet, got %+v", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e.node.FailNext("version", 1, accumulatetest.ErrCodeInternal, "internal error")
+	e.client.StartHealthChecks(ctx, time.Hour)
+
+	deadline := time.Now().Add(time.Second)
+	for e.client.EndpointsStatus()[0].Healthy && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	res = &api.IngestionResponse{}
+	e.get("/v1/ingestion", res)
+	status = res.EndpointsStatus[0]
+	if status.Healthy || status.LastCheck == nil || !strings.Contains(status.LastError, "internal error") {
+		t.Errorf("expected endpoint unhealthy after failed health check, got %+v", status)
+	}
+
+}
+
+func TestIncrementalIngestion(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.cfg.Staking.PageSize = 3
+
+	snapshot := e.ingest()
+	if snapshot.StakingCursor != 4 {
+		t.Errorf("expected cursor 4, got %d", snapshot.StakingCursor)
+	}
+
+	e.addStaker("coreFollower", "acc://follower.acme", "3000000000000")
+
+	// transient node error is retried
+	e.node.FailNext("query-data-set", 1, accumulatetest.ErrCodeInternal, "Internal Error")
+	calls := e.node.Calls("query-data-set")
+
+	snapshot = e.ingest()
+	if snapshot.StakingCursor != 5 {
+		t.Errorf("expected cursor 5, got %d", snapshot.StakingCursor)
+	}
+	if n := e.node.Calls("query-data-set") - calls; n != 2 {
+		t.Errorf("expected 2 query-data-set calls, got %d", n)
+	}
+
+	res := &api.StakingResponse{}
+	e.get("/v1/staking", res)
+	if res.CoreFollower != 1 {
+		t.Errorf("expected 1 core follower, got %d", res.CoreFollower)
+	}
+
+}
+
+// addPayout adds ACME deposit from the staking payout account to rewards account
+func (e *env) addPayout(account, hash, amount string) {
+
+	e.addDeposit(account, hash, e.cfg.Staking.PayoutAccount, amount)
+
+}
+
+// addDeposit adds ACME deposit from source to account
+func (e *env) addDeposit(account, hash, source, amount string) {
+
+	e.node.AddTx(account, &accumulate.QueryTokenTxResponse{
+		Type:   "syntheticDepositTokens",
+		TxHash: hash,
+		TxID:   "acc://" + hash + "@" + strings.TrimPrefix(account, "acc://"),
+		Data: &accumulate.TokenTx{
+			Cause:  "acc://cause" + hash + "@" + strings.TrimPrefix(source, "acc://"),
+			Source: source,
+			Token:  "acc://ACME",
+			Amount: amount,
+		},
+	})
+
+}
+
+func TestRewards(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+
+	e.addPayout("acc://validator.acme/rewards", "01", "100")
+	e.addDeposit("acc://validator.acme/rewards", "02", "acc://someone.acme/tokens", "1000")
+	e.addPayout("acc://validator.acme/rewards", "03", "200")
+	e.node.AddTx("acc://delegator.acme/rewards", &accumulate.QueryTokenTxResponse{
+		Type:   "sendTokens",
+		TxHash: "04",
+		TxID:   "acc://04@staking.acme/payout",
+		Data: &accumulate.TokenTx{
+			From: "acc://staking.acme/payout",
+			To: []*accumulate.TokenTxTo{
+				{URL: "acc://delegator.acme/rewards", Amount: "50"},
+				{URL: "acc://pure.acme/rewards", Amount: "70"},
+			},
+		},
+	})
+
+	// one payout per page exercises the cursor
+	e.cfg.Staking.HistoryPageSize = 1
+	snapshot := e.ingest()
+
+	if snapshot.Stats.NewRewards != 3 {
+		t.Errorf("expected 3 new rewards, got %d", snapshot.Stats.NewRewards)
+	}
+	if snapshot.RewardsCursors["acc://validator.acme/rewards"] != 3 {
+		t.Errorf("expected validator rewards cursor 3, got %d", snapshot.RewardsCursors["acc://validator.acme/rewards"])
+	}
+
+	res := &api.RewardsResponse{}
+	if code := e.get("/v1/staking/rewards?interval=hour", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Total.String() != "350" || res.Payouts != 3 {
+		t.Errorf("expected 3 payouts of 350 in total, got %d of %s", res.Payouts, res.Total)
+	}
+	// payouts found by the first scan were paid at unknown time, they are not put in the current period
+	if res.Backfilled.String() != "350" || res.BackfilledPayouts != 3 || len(res.Periods) != 0 {
+		t.Errorf("expected 3 backfilled payouts of 350 without periods, got %d of %s, periods %+v", res.BackfilledPayouts, res.Backfilled, res.Periods)
+	}
+
+	staker := &api.StakerRewardsResponse{}
+	if code := e.get("/v1/staking/stakers/validator.acme/rewards", staker); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if staker.Identity != "acc://validator.acme" || staker.Total.String() != "300" || staker.Payouts != 2 || len(staker.Result) != 2 {
+		t.Errorf("expected validator with 2 payouts of 300 in total, got %s with %d of %s", staker.Identity, staker.Payouts, staker.Total)
+	}
+
+	// new payouts are picked up from the cursor without duplicates
+	e.addPayout("acc://validator.acme/rewards", "05", "400")
+	snapshot = e.ingest()
+
+	if snapshot.Stats.NewRewards != 1 {
+		t.Errorf("expected 1 new reward, got %d", snapshot.Stats.NewRewards)
+	}
+
+	e.get("/v1/staking/stakers/validator.acme/rewards?count=1&interval=hour", staker)
+	if staker.Total.String() != "700" || staker.Payouts != 3 || len(staker.Result) != 1 || staker.Result[0].TxHash != "05" {
+		t.Errorf("expected the latest payout 05 of 3 payouts of 700 in total, got %d of %s: %+v", staker.Payouts, staker.Total, staker.Result)
+	}
+	if staker.Backfilled.String() != "300" || len(staker.Periods) != 1 || staker.Periods[0].Amount.String() != "400" || staker.Periods[0].Payouts != 1 {
+		t.Errorf("expected a single period of the new payout, got backfilled %s, periods %+v", staker.Backfilled, staker.Periods)
+	}
+
+	if code := e.get("/v1/staking/stakers/unknown.acme/rewards", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown staker, got %d", code)
+	}
+	if code := e.get("/v1/staking/rewards?interval=month", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid interval, got %d", code)
+	}
+
+}
+
+func TestYield(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.addStaker("pure", "acc://dust.acme", "1")
+
+	// tracking started 8 days ago, so only the 7 days window is covered
+	since := time.Now().Add(-8 * 24 * time.Hour)
+	if err := e.store.AppendSupplyPoint(&schema.SupplyPoint{Time: since}); err != nil {
+		t.Fatal(err)
+	}
+
+	// paid before tracking started, so not counted
+	e.addPayout("acc://validator.acme/rewards", "01", "5000000000")
+	e.ingest()
+
+	e.addPayout("acc://validator.acme/rewards", "02", "1000000000")
+	e.addPayout("acc://pure.acme/rewards", "03", "200000000")
+	e.addPayout("acc://dust.acme/rewards", "04", "10000000000")
+	e.ingest()
+
+	res := &api.YieldResponse{}
+	if code := e.get("/v1/staking/yield", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Since == nil || !res.Since.Equal(since) || len(res.Network) != 1 {
+		t.Fatalf("expected tracking start %s and only the covered 7 days window, got %+v", since, res)
+	}
+
+	network := res.Network[0]
+	if network.Window != 7 || network.Days != 7 || network.Rewards.String() != "11200000000" || network.Stake.String() != "18000000000001" {
+		t.Errorf("expected 7 days window with 11200000000 rewards of 18000000000001 stake, got %+v", network)
+	}
+	if apr := network.Rewards.Ratio(network.Stake) * 365 / 7; network.APR != apr || network.APY <= network.APR {
+		t.Errorf("expected APR %f below APY, got %f and %f", apr, network.APR, network.APY)
+	}
+
+	if validators := res.ByType["coreValidator"]; len(validators) != 1 || validators[0].Rewards.String() != "1000000000" || validators[0].Stake.String() != "10000000000000" {
+		t.Errorf("unexpected core validators yield %+v", validators)
+	}
+	if delegated := res.ByType["delegated"]; len(delegated) != 1 || delegated[0].Rewards.Sign() != 0 || delegated[0].APR != 0 {
+		t.Errorf("expected no delegated rewards, got %+v", delegated)
+	}
+
+	staker := &api.StakerResponse{}
+	e.get("/v1/staking/stakers/pure.acme", staker)
+	if len(staker.Yield) != 1 || staker.Yield[0].Window != 7 || staker.Yield[0].Rewards.String() != "200000000" || staker.Yield[0].Stake.String() != "2000000000000" {
+		t.Errorf("unexpected staker yield %+v", staker.Yield)
+	}
+
+	// yield of a dust stake overflows and is omitted
+	dust := &api.StakerResponse{}
+	if code := e.get("/v1/staking/stakers/dust.acme", dust); code != http.StatusOK || len(dust.Yield) != 0 {
+		t.Errorf("expected 200 without yield of dust stake, got %d %+v", code, dust.Yield)
+	}
+
+}
+
+func TestYieldNotCovered(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.ingest()
+
+	e.addPayout("acc://validator.acme/rewards", "01", "1000000000")
+	e.ingest()
+
+	res := &api.YieldResponse{}
+	if code := e.get("/v1/staking/yield", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(res.Network) != 0 || len(res.ByType["coreValidator"]) != 0 {
+		t.Errorf("expected no windows right after tracking started, got %+v", res)
+	}
+
+}
+
+func TestTokens(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.node.AddToken("acc://foo.acme/token", "FOO", 2, "150000", "")
+	e.cfg.Tokens.Issuers = []string{"acc://ACME", "acc://foo.acme/token", "acc://missing.acme/token"}
+
+	snapshot := e.ingest()
+	if !snapshot.Stats.Success || snapshot.Stats.ErrorsTotal != 1 {
+		t.Errorf("expected successful cycle with 1 error of the missing token, got %+v", snapshot.Stats)
+	}
+
+	res := &api.TokensResponse{}
+	if code := e.get("/v1/tokens", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(res.Result) != 2 {
+		t.Fatalf("expected ACME and FOO, got %d tokens", len(res.Result))
+	}
+	if acme := res.Result[0]; acme.Symbol != "ACME" || acme.Staked.String() != "18000000000000" || acme.Circulating.Cmp(acme.Total.Sub(acme.Staked)) != 0 {
+		t.Errorf("expected ACME first with staked supply, got %+v", acme)
+	}
+	if foo := res.Result[1]; foo.URL != "acc://foo.acme/token" || foo.Total.String() != "150000" || foo.Max.Sign() != 0 || foo.Circulating.String() != "150000" || foo.TotalTokens != "1500.00" {
+		t.Errorf("unexpected FOO supply %+v", foo)
+	}
+
+	// failed tokens keep previous supply
+	e.node.FailURL("acc://foo.acme/token", accumulatetest.ErrCodeInternal, "internal error")
+	e.ingest()
+
+	supply := &api.SupplyResponse{}
+	if code := e.get("/v1/tokens/"+url.PathEscape("acc://FOO.acme/token")+"/supply", supply); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if supply.Symbol != "FOO" || supply.Total.String() != "150000" {
+		t.Errorf("expected previous FOO supply, got %+v", supply)
+	}
+
+	acme := &api.SupplyResponse{}
+	e.get("/v1/tokens/acme/supply", acme)
+	if acme.Symbol != "ACME" || acme.Staked.String() != "18000000000000" {
+		t.Errorf("expected ACME supply with stake, got %+v", acme)
+	}
+
+	if code := e.get("/v1/tokens/missing.acme%2Ftoken/supply", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for untracked token, got %d", code)
+	}
+
+}
+
+// getRaw calls API and returns status, content type and body as is
+func (e *env) getRaw(path string) (int, string, string) {
+
+	e.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	e.api.HTTP.ServeHTTP(rec, req)
+
+	return rec.Code, rec.Header().Get("Content-Type"), rec.Body.String()
+
+}
+
+func TestAggregatorSupply(t *testing.T) {
+
+	e := newEnv(t)
+	e.node.AddToken("acc://ACME", "ACME", 8, "21091473519485401", "50000000000000000")
+	e.addStaker("pure", "acc://pure.acme", "5000000000000000")
+	e.cfg.Aggregators.CoinMarketCapDecimals = 2
+	e.ingest()
+
+	plain := []struct {
+		path string
+		body string
+	}{
+		{"/v1/supply/coingecko/total", "210914735.19485401"},
+		{"/v1/supply/coingecko/circulating", "160914735.19485401"},
+		{"/v1/supply/coingecko/max", "500000000.00000000"},
+		{"/v1/supply/coinmarketcap/total", "210914735.19"},
+		{"/v1/supply/coinmarketcap/circulating", "160914735.19"},
+		{"/v1/supply/coinmarketcap/max", "500000000.00"},
+	}
+
+	for _, tc := range plain {
+		code, contentType, body := e.getRaw(tc.path)
+		if code != http.StatusOK || !strings.HasPrefix(contentType, "text/plain") || body != tc.body {
+			t.Errorf("GET %s: expected 200 text/plain %q, got %d %s %q", tc.path, tc.body, code, contentType, body)
+		}
+	}
+
+	jsonBodies := map[string]string{
+		"/v1/supply/coingecko":     `{"total_supply":210914735.19485401,"circulating_supply":160914735.19485401,"max_supply":500000000.00000000}`,
+		"/v1/supply/coinmarketcap": `{"total_supply":210914735.19,"circulating_supply":160914735.19,"max_supply":500000000.00}`,
+	}
+
+	for path, expected := range jsonBodies {
+		code, contentType, body := e.getRaw(path)
+		if code != http.StatusOK || !strings.HasPrefix(contentType, "application/json") || strings.TrimSpace(body) != expected {
+			t.Errorf("GET %s: expected 200 JSON %s, got %d %s %s", path, expected, code, contentType, body)
+		}
+	}
+
+	if code, _, _ := e.getRaw("/v1/supply/coingecko/staked"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown kind, got %d", code)
+	}
+
+}
+
+func TestAggregatorSupplyWithoutLimit(t *testing.T) {
+
+	e := newEnv(t)
+	e.node.AddToken("acc://ACME", "ACME", 8, "21091473519485401", "")
+	e.ingest()
+
+	if code, _, body := e.getRaw("/v1/supply/coinmarketcap"); code != http.StatusOK || strings.TrimSpace(body) != `{"total_supply":210914735.19485401,"circulating_supply":210914735.19485401,"max_supply":null}` {
+		t.Errorf("expected null max supply, got %d %s", code, body)
+	}
+	if code, _, _ := e.getRaw("/v1/supply/coinmarketcap/max"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for max supply without limit, got %d", code)
+	}
+
+}
+
+func TestLockedSupply(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.node.AddTokenAccount("acc://treasury.acme/tokens", "acc://ACME", "5000000000000000")
+	e.node.AddTokenAccount("acc://vesting.acme/tokens", "acc://ACME", "100000000000")
+	e.node.AddTokenAccount("acc://foo.acme/tokens", "acc://foo.acme/token", "100000000000")
+	e.cfg.Supply.LockedAccounts = []config.LockedAccount{
+		{Label: "Treasury", URL: "acc://treasury.acme/tokens"},
+		{Label: "Vesting", URL: "acc://VESTING.acme/tokens"},
+		{Label: "Staked treasury", URL: "acc://pure.acme/staking"},
+		{Label: "Not ACME", URL: "acc://foo.acme/tokens"},
+	}
+
+	snapshot := e.ingest()
+	if !snapshot.Stats.Success || snapshot.Stats.ErrorsTotal != 1 {
+		t.Errorf("expected successful cycle with 1 error of the non-ACME account, got %+v", snapshot.Stats)
+	}
+
+	res := &api.SupplyResponse{}
+	if code := e.get("/v1/supply", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Locked.String() != "5000100000000000" || res.LockedTokens != "50001000.00000000" {
+		t.Errorf("expected locked 5000100000000000, got %s (%s)", res.Locked, res.LockedTokens)
+	}
+	if res.Staked.String() != "18000000000000" || res.Circulating.String() != "24981900000000000" {
+		t.Errorf("expected staked 18000000000000 and circulating 24981900000000000, got %s and %s", res.Staked, res.Circulating)
+	}
+	if res.Methodology != api.ACMESupplyMethodology {
+		t.Errorf("unexpected methodology %q", res.Methodology)
+	}
+
+	if len(res.LockedAccounts) != 4 {
+		t.Fatalf("expected 4 locked accounts, got %d", len(res.LockedAccounts))
+	}
+	if a := res.LockedAccounts[0]; a.Label != "Treasury" || a.BalanceTokens != "50000000.00000000" || a.Staked || a.UpdatedAt == nil {
+		t.Errorf("unexpected treasury %+v", a.LockedAccount)
+	}
+	if a := res.LockedAccounts[2]; !a.Staked || a.Balance.String() != "2000000000000" {
+		t.Errorf("expected staking account counted as staked, got %+v", a.LockedAccount)
+	}
+	if a := res.LockedAccounts[3]; a.Balance.Sign() != 0 || a.UpdatedAt != nil {
+		t.Errorf("expected non-ACME account not counted, got %+v", a.LockedAccount)
+	}
+
+	if _, _, body := e.getRaw("/v1/supply/coingecko/circulating"); body != "249819000.00000000" {
+		t.Errorf("expected aggregator circulating supply without locked accounts, got %q", body)
+	}
+
+	points := e.store.SupplyHistory(time.Time{}, time.Now())
+	if p := points[len(points)-1]; p.Locked.Cmp(res.Locked) != 0 || p.Circulating.Cmp(res.Circulating) != 0 {
+		t.Errorf("expected supply point with locked %s and circulating %s, got %+v", res.Locked, res.Circulating, p)
+	}
+
+	// failed accounts keep previous balance
+	e.node.FailURL("acc://vesting.acme/tokens", accumulatetest.ErrCodeInternal, "internal error")
+	e.ingest()
+
+	res = &api.SupplyResponse{}
+	e.get("/v1/supply", res)
+	if res.Locked.String() != "5000100000000000" || res.LockedAccounts[1].Balance.String() != "100000000000" {
+		t.Errorf("expected previous vesting balance, got locked %s", res.Locked)
+	}
+
+}
+
+// addBurn adds ACME burn to tx history of ACME issuer
+func (e *env) addBurn(hash, amount string, refund bool) {
+
+	e.node.AddTx(e.cfg.ACME.TokenIssuer, &accumulate.QueryTokenTxResponse{
+		Type:   "syntheticBurnTokens",
+		TxHash: hash,
+		TxID:   "acc://" + hash + "@acme",
+		Data: &accumulate.TokenTx{
+			Cause:    "acc://cause" + hash + "@buyer.acme/tokens",
+			Amount:   amount,
+			IsRefund: refund,
+		},
+	})
+
+}
+
+func TestSupplyFlows(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.cfg.Staking.HistoryPageSize = 1
+
+	// burns before the first scan can not be dated and are not counted
+	e.addBurn("00", "500000000", false)
+	e.ingest()
+
+	if flows := e.store.SupplyFlows(time.Time{}, time.Now()); len(flows) != 0 {
+		t.Errorf("expected no flows after the first cycle, got %d", len(flows))
+	}
+
+	e.addBurn("01", "200000000", false)
+	e.addBurn("02", "100000000", false)
+	e.addBurn("03", "700000000", true)
+	e.addDeposit(e.cfg.ACME.TokenIssuer, "04", "acc://buyer.acme/tokens", "900000000")
+	e.node.AddToken("acc://ACME", "ACME", 8, "30000100000000000", "50000000000000000")
+	e.ingest()
+	snapshot := e.ingest()
+
+	res := &api.SupplyFlowsResponse{}
+	if code := e.get("/v1/supply/flows", res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.NetChange.String() != "100000000000" || res.Burned.String() != "300000000" || res.Issued.String() != "100300000000" || res.Burns != 2 {
+		t.Errorf("unexpected flows: issued %s, burned %s, net change %s, burns %d", res.Issued, res.Burned, res.NetChange, res.Burns)
+	}
+	if res.IssuedTokens != "1003.00000000" || res.BurnedTokens != "3.00000000" || res.NetChangeTokens != "1000.00000000" {
+		t.Errorf("unexpected tokens: issued %s, burned %s, net change %s", res.IssuedTokens, res.BurnedTokens, res.NetChangeTokens)
+	}
+	if len(res.Result) == 0 || !res.Result[0].Time.Equal(res.Result[0].Time.Truncate(24*time.Hour)) {
+		t.Errorf("expected daily flows, got %+v", res.Result)
+	}
+	if res.SnapshotID != snapshot.ID {
+		t.Errorf("expected snapshot %d, got %d", snapshot.ID, res.SnapshotID)
+	}
+
+	week := &api.SupplyFlowsResponse{}
+	e.get("/v1/supply/flows?interval=week", week)
+	if week.Issued.Cmp(res.Issued) != 0 || len(week.Result) == 0 {
+		t.Errorf("expected weekly flows with the same totals, got %+v", week)
+	}
+
+	if code := e.get("/v1/supply/flows?interval=hour", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for hourly flows, got %d", code)
+	}
+
+	// burns of a failed cycle are counted once supply is fetched again
+	e.addBurn("05", "100000000", false)
+	e.node.AddToken("acc://ACME", "ACME", 8, "30000099900000000", "50000000000000000")
+	e.node.FailURL("acc://ACME", accumulatetest.ErrCodeInternal, "internal error")
+	e.ingest()
+	e.node.FailURL("acc://ACME", 0, "")
+	e.ingest()
+
+	res = &api.SupplyFlowsResponse{}
+	e.get("/v1/supply/flows", res)
+	if res.NetChange.String() != "99900000000" || res.Burned.String() != "400000000" || res.Issued.String() != "100300000000" || res.Burns != 3 {
+		t.Errorf("unexpected flows after failed cycle: issued %s, burned %s, net change %s, burns %d", res.Issued, res.Burned, res.NetChange, res.Burns)
+	}
+
+}
+
+func TestSupplyHistory(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+
+	e.ingest()
+	e.node.AddToken("acc://ACME", "ACME", 8, "30010000000000000", "50000000000000000")
+	e.ingest()
+	e.node.AddToken("acc://ACME", "ACME", 8, "29990000000000000", "50000000000000000")
+	e.ingest()
+
+	points := e.store.SupplyHistory(time.Time{}, time.Now())
+	if len(points) != 3 {
+		t.Fatalf("expected 3 supply points, got %d", len(points))
+	}
+
+	hour := points[0].Time.UTC().Truncate(time.Hour)
+	if !points[2].Time.UTC().Truncate(time.Hour).Equal(hour) {
+		t.Skip("cycles crossed an hour boundary")
+	}
+
+	// a point two hours later falls into its own candle
+	later := &schema.SupplyPoint{Time: hour.Add(2*time.Hour + 30*time.Minute), SnapshotID: 100, Total: schema.NewAmount(1), Staked: schema.NewAmount(1)}
+	if err := e.store.AppendSupplyPoint(later); err != nil {
+		t.Fatal(err)
+	}
+
+	res := &api.SupplyHistoryResponse{}
+	path := "/v1/supply/history?interval=hour&from=" + hour.Format(time.RFC3339) + "&to=" + hour.Add(3*time.Hour).Format(time.RFC3339)
+	if code := e.get(path, res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	if res.Interval != "hour" || len(res.Result) != 2 {
+		t.Fatalf("expected 2 hourly candles, got %d", len(res.Result))
+	}
+
+	candle := res.Result[0]
+	if !candle.Time.Equal(hour) || candle.Points != 3 {
+		t.Errorf("expected 3 points at %s, got %d at %s", hour, candle.Points, candle.Time)
+	}
+	if c := candle.Total; c.Open.String() != "30000000000000000" || c.Close.String() != "29990000000000000" || c.Min.String() != "29990000000000000" || c.Max.String() != "30010000000000000" {
+		t.Errorf("unexpected total candle %+v", c)
+	}
+	if c := candle.Staked; c.Open.String() != "18000000000000" || c.Close.String() != "18000000000000" {
+		t.Errorf("unexpected staked candle %+v", c)
+	}
+	if c := candle.Locked; c == nil || c.Open.Sign() != 0 || c.Max.Sign() != 0 {
+		t.Errorf("expected zero locked candle, got %+v", c)
+	}
+
+	if candle = res.Result[1]; !candle.Time.Equal(hour.Add(2*time.Hour)) || candle.Points != 1 || candle.Total.Open.String() != "1" || candle.Total.Close.String() != "1" {
+		t.Errorf("expected single point candle at %s, got %+v", hour.Add(2*time.Hour), candle)
+	}
+
+	if code := e.get("/v1/supply/history?interval=minute", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown interval, got %d", code)
+	}
+
+}
+
+func TestV3EmptyStakingEntry(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.node.AddEmptyDataEntry(stakingAccount, "aa")
+	e.addStaker("pure", "acc://late.acme", "3000000000000")
+
+	// the entry without data is skipped, it does not stall ingestion at its cursor
+	snapshot := e.ingestWith(ingest.NewIngestor(e.cfg, accumulate.NewAccumulateV3Client(e.client), e.store))
+	if !snapshot.Stats.Success || len(snapshot.StakingRecords) != 5 || snapshot.StakingCursor != 6 {
+		t.Errorf("expected successful cycle with 5 records at cursor 6, got success %v, %d records at cursor %d: %v", snapshot.Stats.Success, len(snapshot.StakingRecords), snapshot.StakingCursor, snapshot.Stats.Errors)
+	}
+
+}
+
+func TestRescanKeepsBalances(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.ingest()
+
+	ingestor := ingest.NewIngestor(e.cfg, e.client, e.store)
+	ingestor.Rescan()
+
+	// balance of a rescanned record fails, it keeps the balance of the previous snapshot
+	e.node.FailURL("acc://pure.acme/staking", accumulatetest.ErrCodeInternal, "internal error")
+
+	snapshot := e.ingestWith(ingestor)
+	if snapshot.Stats.Balances.Failed != 1 {
+		t.Errorf("expected 1 failed balance, got %+v", snapshot.Stats.Balances)
+	}
+
+	record := store.SearchStakingRecordByIdentity(snapshot.StakingRecords, "acc://pure.acme")
+	if record == nil || record.Balance.String() != "2000000000000" {
+		t.Errorf("expected previous balance 2000000000000 of acc://pure.acme, got %+v", record)
+	}
+
+	res := &api.SupplyResponse{}
+	e.get("/v1/supply", res)
+	if res.Staked.String() != "18000000000000" {
+		t.Errorf("expected staked 18000000000000, got %s", res.Staked)
+	}
+
+}
+
+func TestStakerHistory(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	ingestor := ingest.NewIngestor(e.cfg, e.client, e.store)
+	e.ingestWith(ingestor)
+
+	// delegator moves its stake to another validator
+	e.addDelegator("delegated", "acc://delegator.acme", "acc://staking-validator.acme", "5000000000000")
+	e.ingestWith(ingestor)
+
+	check := func(stage string) {
+
+		t.Helper()
+
+		res := &api.StakerHistoryResponse{}
+		if code := e.get("/v1/staking/stakers/delegator.acme/history", res); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", stage, code)
+		}
+
+		if res.Identity != "acc://delegator.acme" || len(res.Result) != 2 {
+			t.Fatalf("%s: expected 2 versions of acc://delegator.acme, got %d of %s", stage, len(res.Result), res.Identity)
+		}
+
+		first, second := res.Result[0], res.Result[1]
+		if first.ChainIndex != 1 || first.Delegate != "acc://validator.acme" || len(first.Changes) != 0 {
+			t.Errorf("%s: unexpected first version %+v", stage, first)
+		}
+		if second.ChainIndex != 4 || second.Delegate != "acc://staking-validator.acme" || len(second.Changes) != 1 || second.Changes[0] != "delegate" {
+			t.Errorf("%s: expected delegate change at chain index 4, got %+v", stage, second)
+		}
+
+	}
+
+	check("incremental")
+
+	// rescan reads every entry again, which must not duplicate versions
+	ingestor.Rescan()
+	snapshot := e.ingestWith(ingestor)
+	if !snapshot.Stats.Success || snapshot.StakingCursor != 5 {
+		t.Fatalf("expected successful rescan to cursor 5, got success %v at cursor %d", snapshot.Stats.Success, snapshot.StakingCursor)
+	}
+
+	check("rescan")
+
+	if code := e.get("/v1/staking/stakers/unknown.acme/history", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown staker, got %d", code)
+	}
+
+}
+
+func TestFailedRescan(t *testing.T) {
+
+	e := newPopulatedEnv(t)
+	e.cfg.Staking.PageSize = 3
+	e.ingest()
+
+	ingestor := ingest.NewIngestor(e.cfg, e.client, e.store)
+	ingestor.Rescan()
+
+	// the first page of the rescan fails with every retry
+	e.node.FailNext("query-data-set", 2, accumulatetest.ErrCodeInternal, "internal error")
+
+	snapshot := e.ingestWith(ingestor)
+	if snapshot.Stats.Success || len(snapshot.StakingRecords) != 4 || snapshot.StakingCursor != 4 {
+		t.Errorf("expected failed cycle keeping 4 records at cursor 4, got success %v, %d records at cursor %d", snapshot.Stats.Success, len(snapshot.StakingRecords), snapshot.StakingCursor)
+	}
+
+	res := &api.SupplyResponse{}
+	e.get("/v1/supply", res)
+	if res.Staked.String() != "18000000000000" {
+		t.Errorf("expected staked 18000000000000 from the previous records, got %s", res.Staked)
+	}
+
+	// the next cycle repeats the rescan, which reads both pages from the start
+	calls := e.node.Calls("query-data-set")
+	snapshot = e.ingestWith(ingestor)
+	if !snapshot.Stats.Success || len(snapshot.StakingRecords) != 4 || snapshot.StakingCursor != 4 {
+		t.Errorf("expected successful rescan of 4 records, got success %v, %d records at cursor %d", snapshot.Stats.Success, len(snapshot.StakingRecords), snapshot.StakingCursor)
+	}
+	if n := e.node.Calls("query-data-set") - calls; n != 2 {
+		t.Errorf("expected rescan of 2 pages, got %d calls", n)
+	}
+
+	// an incremental cycle reads only the empty page after the cursor
+	calls = e.node.Calls("query-data-set")
+	e.ingestWith(ingestor)
+	if n := e.node.Calls("query-data-set") - calls; n != 1 {
+		t.Errorf("expected incremental cycle of 1 call, got %d", n)
+	}
+
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/labstack/gommon/log"
+)
+
+// fetchBalances updates ACME balances of stakers using a bounded pool of workers.
+// Every record is updated by a single worker; records with failed requests keep their previous balance.
+// Canceling ctx stops sending new requests.
+func (i *Ingestor) fetchBalances(ctx context.Context, records []*schema.StakingRecord, errs *errorList) *schema.RequestStats {
+
+	stats := &schema.RequestStats{Requested: int64(len(records))}
+
+	jobs := make(chan *schema.StakingRecord)
+	wg := &sync.WaitGroup{}
+
+	for w := 0; w < i.Config.Ingest.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range jobs {
+				if err := i.fetchBalance(ctx, record); err != nil {
+					err = fmt.Errorf("can not fetch balance of %s: %s", record.Stake, err)
+					log.Error(err)
+					errs.add(err)
+					atomic.AddInt64(&stats.Failed, 1)
+					continue
+				}
+				atomic.AddInt64(&stats.Succeeded, 1)
+			}
+		}()
+	}
+
+feed:
+	for _, record := range records {
+		select {
+		case jobs <- record:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return stats
+
+}
+
+// fetchBalance gets ACME balance of staking record
+func (i *Ingestor) fetchBalance(ctx context.Context, record *schema.StakingRecord) error {
+
+	balance, err := i.Client.QueryTokenAccount(ctx, &accumulate.Params{URL: record.Stake})
+	if err != nil {
+		return err
+	}
+
+	record.Balance, err = schema.ParseAmount(balance.Data.Balance)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
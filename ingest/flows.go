@@ -0,0 +1,66 @@
+package ingest
+
+import (
+	"context"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/labstack/gommon/log"
+)
+
+// fetchBurns pages through tx history of ACME issuer from cursor and sums burn transactions.
+// Transactions carry no timestamps, so the first scan (nil cursor) only moves the cursor to the end of the history,
+// and burns are counted from then on. On error, burns of the scanned pages are returned with the cursor after them.
+// A missing history has no burns yet.
+func (i *Ingestor) fetchBurns(ctx context.Context, cursor *int64) (schema.Amount, int64, *int64, error) {
+
+	burned := schema.Amount{}
+	burns := int64(0)
+
+	if cursor == nil {
+		history, err := i.Client.QueryTxHistory(ctx, &accumulate.Params{URL: i.Config.ACME.TokenIssuer, Count: 1})
+		if accumulate.IsNotFound(err) {
+			return burned, burns, new(int64), nil
+		}
+		if err != nil {
+			return burned, burns, nil, err
+		}
+		return burned, burns, &history.Total, nil
+	}
+
+	start, err := i.scanTxHistory(ctx, i.Config.ACME.TokenIssuer, *cursor, func(txs []*accumulate.QueryTokenTxResponse) error {
+
+		for _, tx := range txs {
+
+			amount, err := burnAmount(tx)
+			if err != nil {
+				log.Error("can not parse burn ", tx.TxID, ": ", err)
+				continue
+			}
+			if amount.Sign() == 0 {
+				continue
+			}
+
+			burned = burned.Add(amount)
+			burns++
+
+		}
+
+		return nil
+
+	})
+
+	return burned, burns, &start, err
+
+}
+
+// burnAmount returns ACME amount burned by tx of the issuer history, 0 if tx is not a burn
+func burnAmount(tx *accumulate.QueryTokenTxResponse) (schema.Amount, error) {
+
+	if tx.Type != "syntheticBurnTokens" || tx.Data == nil || tx.Data.IsRefund {
+		return schema.Amount{}, nil
+	}
+
+	return schema.ParseAmount(tx.Data.Amount)
+
+}
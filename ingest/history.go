@@ -0,0 +1,40 @@
+package ingest
+
+import (
+	"context"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+)
+
+// scanTxHistory pages through tx history of account from cursor and passes transactions of every page to handle.
+// It returns the cursor after the handled pages, so a failed page is requested again by the next scan.
+// A missing account has no history yet.
+func (i *Ingestor) scanTxHistory(ctx context.Context, account string, cursor int64, handle func(txs []*accumulate.QueryTokenTxResponse) error) (int64, error) {
+
+	for {
+
+		history, err := i.Client.QueryTxHistory(ctx, &accumulate.Params{URL: account, Start: cursor, Count: i.Config.Staking.HistoryPageSize})
+		if accumulate.IsNotFound(err) {
+			return cursor, nil
+		}
+		if err != nil {
+			return cursor, err
+		}
+
+		if err = handle(history.Items); err != nil {
+			return cursor, err
+		}
+
+		// the node may skip transactions of a page, so the cursor moves by the requested range
+		cursor += i.Config.Staking.HistoryPageSize
+		if cursor > history.Total {
+			cursor = history.Total
+		}
+
+		if cursor >= history.Total {
+			return cursor, nil
+		}
+
+	}
+
+}
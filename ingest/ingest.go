@@ -0,0 +1,382 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/config"
+	"github.com/AccumulateNetwork/metrics-api/metrics"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/gommon/log"
+)
+
+// maxCycleErrors limits number of error messages kept in cycle stats
+const maxCycleErrors = 20
+
+type Ingestor struct {
+	Config *config.Config
+	Client accumulate.Client
+	Store  store.Store
+	rescan chan struct{}
+}
+
+// NewIngestor constructs the ingestor
+func NewIngestor(cfg *config.Config, client accumulate.Client, st store.Store) *Ingestor {
+
+	return &Ingestor{
+		Config: cfg,
+		Client: client,
+		Store:  st,
+		rescan: make(chan struct{}, 1),
+	}
+
+}
+
+// Rescan requests full rescan of the staking data account, it starts immediately
+func (i *Ingestor) Rescan() {
+
+	select {
+	case i.rescan <- struct{}{}:
+	default:
+		// rescan is already requested
+	}
+
+}
+
+// Run runs ingestion cycles until ctx is canceled, a canceled cycle is dropped without saving
+func (i *Ingestor) Run(ctx context.Context) {
+
+	for {
+
+		rescan := false
+
+		select {
+		case <-i.rescan:
+			rescan = true
+		default:
+		}
+
+		wake := i.rescan
+
+		// an incomplete rescan is requested again and retried by the next cycle on schedule
+		if i.cycle(ctx, rescan) {
+			i.Rescan()
+			wake = nil
+		}
+
+		select {
+		case <-time.After(time.Duration(i.Config.Ingest.Interval)):
+		case <-wake:
+			// wake up and pass the request to the next cycle
+			i.Rescan()
+		case <-ctx.Done():
+			log.Info("ingestion stopped")
+			return
+		}
+
+	}
+
+}
+
+// cycle builds new snapshot from the previous one, fetching ACME supply and burns, new staking entries,
+// balances of stakers and locked accounts and rewards payouts, and saves it.
+// The cycle succeeds if ACME supply and staking entries are fetched, only a successful cycle moves snapshot UpdatedAt.
+// A rescan publishes records only once the whole data account is read, cycle returns true if it has to be retried.
+func (i *Ingestor) cycle(ctx context.Context, rescan bool) bool {
+
+	stats := &schema.CycleStats{StartedAt: time.Now(), Concurrency: i.Config.Ingest.Concurrency}
+	served := i.Client.ServedCalls()
+	errs := &errorList{}
+	success := true
+
+	prev := i.Store.Snapshot()
+	if prev == nil {
+		prev = &schema.Snapshot{}
+	}
+
+	// work on copies, so the previous snapshot stays untouched for readers
+	snapshot := &schema.Snapshot{ID: prev.ID + 1, StakingRecords: []*schema.StakingRecord{}, Stats: stats}
+
+	acme, err := i.fetchACME(ctx)
+	if err != nil {
+		err = fmt.Errorf("can not fetch ACME supply, keeping previous values: %s", err)
+		log.Error(err)
+		errs.add(err)
+		acme = prev.ACME
+		success = false
+	}
+
+	snapshot.ACME = acme
+	snapshot.Tokens = i.fetchTokens(ctx, acme, prev.Tokens, errs)
+
+	// scan ACME issuer for burns only with fetched supply, so burns are matched with the change of issued supply
+	flow := &schema.SupplyFlow{}
+	snapshot.BurnsCursor = prev.BurnsCursor
+
+	if success {
+		flow.Burned, flow.Burns, snapshot.BurnsCursor, err = i.fetchBurns(ctx, prev.BurnsCursor)
+		if err != nil {
+			err = fmt.Errorf("can not fetch burns of %s: %s", i.Config.ACME.TokenIssuer, err)
+			log.Error(err)
+			errs.add(err)
+		}
+		if prev.ACME != nil {
+			flow.NetChange = acme.Total.Sub(prev.ACME.Total)
+		}
+		flow.Issued = flow.NetChange.Add(flow.Burned)
+	}
+
+	// records are flat and amounts are immutable, so shallow copies are deep enough
+	prevRecords := []*schema.StakingRecord{}
+	for _, r := range prev.StakingRecords {
+		record := *r
+		prevRecords = append(prevRecords, &record)
+	}
+
+	records, cursor := prevRecords, prev.StakingCursor
+	if rescan {
+		log.Info("full rescan of ", i.Config.Staking.DataAccount, " requested")
+		records, cursor = []*schema.StakingRecord{}, 0
+	}
+
+	records, cursor, err = i.fetchStakingRecords(ctx, records, cursor)
+	if err != nil {
+		err = fmt.Errorf("can not fetch staking entries from %s: %s", i.Config.Staking.DataAccount, err)
+		log.Error(err)
+		errs.add(err)
+		success = false
+		// a partial registry would understate stake, so the previous records are kept until the rescan completes
+		if rescan {
+			log.Info("full rescan of ", i.Config.Staking.DataAccount, " did not complete, keeping previous records")
+			records, cursor = prevRecords, prev.StakingCursor
+		}
+	}
+
+	// rescanned records start with zero balances, failed balance requests must keep the previous ones
+	if rescan && err == nil {
+		seedBalances(records, prevRecords)
+	}
+
+	snapshot.StakingRecords, snapshot.StakingCursor = records, cursor
+	retryRescan := rescan && err != nil
+
+	log.Info("total staking records: ", len(snapshot.StakingRecords))
+
+	// get ACME balances of stakers
+	stats.Balances = i.fetchBalances(ctx, snapshot.StakingRecords, errs)
+
+	// get ACME balances of accounts excluded from circulating supply
+	snapshot.LockedAccounts = i.fetchLockedAccounts(ctx, prev.LockedAccounts, snapshot.StakingRecords, errs)
+
+	// scan rewards accounts for new payouts
+	snapshot.RewardsCursors = make(map[string]int64, len(prev.RewardsCursors))
+	for account, cursor := range prev.RewardsCursors {
+		snapshot.RewardsCursors[account] = cursor
+	}
+
+	stats.Rewards, stats.NewRewards = i.fetchRewards(ctx, snapshot.StakingRecords, snapshot.RewardsCursors, errs)
+
+	if ctx.Err() != nil {
+		log.Info("ingestion cycle canceled, snapshot ", snapshot.ID, " dropped")
+		return rescan
+	}
+
+	now := time.Now()
+
+	snapshot.UpdatedAt = prev.UpdatedAt
+	if success {
+		snapshot.UpdatedAt = now
+	}
+
+	stats.Duration = now.Sub(stats.StartedAt).Seconds()
+	stats.Endpoint, stats.Endpoints = servedDiff(served, i.Client.ServedCalls())
+	stats.Success = success
+	stats.Errors = errs.list
+	stats.ErrorsTotal = errs.total
+
+	metrics.ObserveCycle(stats)
+
+	if err = i.Store.SaveSnapshot(snapshot); err != nil {
+		log.Error(err)
+		return rescan
+	}
+
+	metrics.ObserveSnapshot(snapshot)
+
+	// the first cycle has no previous supply to compare with
+	if prev.ACME != nil {
+		flow.Time = now
+		if err = i.Store.AddSupplyFlow(flow); err != nil {
+			log.Error("can not record supply flow: ", err)
+		}
+	}
+
+	if success {
+		point := &schema.SupplyPoint{
+			Time:        snapshot.UpdatedAt,
+			SnapshotID:  snapshot.ID,
+			Total:       snapshot.ACME.Total,
+			Max:         snapshot.ACME.Max,
+			Staked:      store.GetTotalStake(snapshot.StakingRecords),
+			Locked:      store.GetLockedSupply(snapshot.LockedAccounts),
+			Circulating: store.GetCirculatingSupply(snapshot),
+		}
+		if err = i.Store.AppendSupplyPoint(point); err != nil {
+			log.Error("can not record supply point: ", err)
+		}
+	}
+
+	log.Info("saved snapshot ", snapshot.ID, " in ", stats.Duration, "s, balances: ", stats.Balances.Succeeded, " succeeded, ", stats.Balances.Failed, " failed, new rewards: ", stats.NewRewards, ", served by ", stats.Endpoint)
+
+	return retryRescan
+
+}
+
+// fetchACME gets ACME token issuer and parses its supply
+func (i *Ingestor) fetchACME(ctx context.Context) (*schema.ACME, error) {
+
+	token, err := i.fetchToken(ctx, i.Config.ACME.TokenIssuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema.ACME{Symbol: token.Symbol, Precision: token.Precision, Total: token.Total, Max: token.Max}, nil
+
+}
+
+// fetchTokens gets supply of configured token issuers besides ACME, which is passed as fetched.
+// Failed tokens keep their previous values, they do not fail the cycle.
+func (i *Ingestor) fetchTokens(ctx context.Context, acme *schema.ACME, prev []*schema.Token, errs *errorList) []*schema.Token {
+
+	res := []*schema.Token{}
+
+	if acme != nil {
+		res = append(res, &schema.Token{URL: i.Config.ACME.TokenIssuer, Symbol: acme.Symbol, Precision: acme.Precision, Total: acme.Total, Max: acme.Max})
+	}
+
+	seen := map[string]bool{store.TrimScheme(i.Config.ACME.TokenIssuer): true}
+
+	for _, issuer := range i.Config.Tokens.Issuers {
+
+		if seen[store.TrimScheme(issuer)] {
+			continue
+		}
+		seen[store.TrimScheme(issuer)] = true
+
+		token, err := i.fetchToken(ctx, issuer)
+		if err != nil {
+			err = fmt.Errorf("can not fetch supply of %s, keeping previous values: %s", issuer, err)
+			log.Error(err)
+			errs.add(err)
+			token = searchToken(prev, issuer)
+		}
+
+		if token != nil {
+			res = append(res, token)
+		}
+
+	}
+
+	return res
+
+}
+
+// fetchToken gets token issuer and parses its supply
+func (i *Ingestor) fetchToken(ctx context.Context, issuer string) (*schema.Token, error) {
+
+	token := &schema.Token{URL: issuer}
+
+	tokenData, err := i.Client.QueryToken(ctx, &accumulate.Params{URL: issuer})
+	if err != nil {
+		return nil, err
+	}
+
+	token.Symbol = tokenData.Data.Symbol
+	token.Precision = tokenData.Data.Precision
+
+	// amounts are omitted by the node for tokens that were never issued or have no supply limit
+	if tokenData.Data.Issued != "" {
+		token.Total, err = schema.ParseAmount(tokenData.Data.Issued)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tokenData.Data.SupplyLimit != "" {
+		token.Max, err = schema.ParseAmount(tokenData.Data.SupplyLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+
+}
+
+// searchToken searches token by issuer URL (case insensitive)
+func searchToken(tokens []*schema.Token, issuer string) *schema.Token {
+
+	for _, t := range tokens {
+		if store.TrimScheme(t.URL) == store.TrimScheme(issuer) {
+			return t
+		}
+	}
+
+	return nil
+
+}
+
+// seedBalances sets balances of records to balances of previous records with the same identity
+func seedBalances(records, prevRecords []*schema.StakingRecord) {
+
+	balances := make(map[string]schema.Amount, len(prevRecords))
+	for _, r := range prevRecords {
+		balances[store.TrimScheme(r.Identity)] = r.Balance
+	}
+
+	for _, r := range records {
+		r.Balance = balances[store.TrimScheme(r.Identity)]
+	}
+
+}
+
+// servedDiff returns number of calls served by every endpoint between two counters and the endpoint that served most
+func servedDiff(before, after map[string]int64) (string, map[string]int64) {
+
+	top := ""
+	res := make(map[string]int64, len(after))
+
+	for url, n := range after {
+		res[url] = n - before[url]
+		if res[url] > 0 && (top == "" || res[url] > res[top] || res[url] == res[top] && url < top) {
+			top = url
+		}
+	}
+
+	return top, res
+
+}
+
+// errorList collects errors of a cycle from concurrent workers
+type errorList struct {
+	mu    sync.Mutex
+	list  []string
+	total int
+}
+
+// add records error, only the first maxCycleErrors messages are kept
+func (l *errorList) add(err error) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total++
+	if len(l.list) < maxCycleErrors {
+		l.list = append(l.list, err.Error())
+	}
+
+}
@@ -0,0 +1,86 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/gommon/log"
+)
+
+// fetchLockedAccounts gets balances of configured locked accounts. Failed accounts keep their previous balance,
+// they do not fail the cycle. Accounts that are staking accounts of records are marked as staked.
+func (i *Ingestor) fetchLockedAccounts(ctx context.Context, prev []*schema.LockedAccount, records []*schema.StakingRecord, errs *errorList) []*schema.LockedAccount {
+
+	res := []*schema.LockedAccount{}
+
+	stakes := make(map[string]bool, len(records))
+	for _, r := range records {
+		stakes[store.TrimScheme(r.Stake)] = true
+	}
+
+	for _, cfg := range i.Config.Supply.LockedAccounts {
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		account := &schema.LockedAccount{Label: cfg.Label, URL: cfg.URL, Staked: stakes[store.TrimScheme(cfg.URL)]}
+		if p := searchLockedAccount(prev, cfg.URL); p != nil {
+			account.Balance = p.Balance
+			account.UpdatedAt = p.UpdatedAt
+		}
+
+		if err := i.fetchLockedBalance(ctx, account); err != nil {
+			err = fmt.Errorf("can not fetch balance of locked account %s, keeping previous value: %s", cfg.URL, err)
+			log.Error(err)
+			errs.add(err)
+		}
+
+		res = append(res, account)
+
+	}
+
+	return res
+
+}
+
+// fetchLockedBalance gets ACME balance of locked account
+func (i *Ingestor) fetchLockedBalance(ctx context.Context, account *schema.LockedAccount) error {
+
+	balance, err := i.Client.QueryTokenAccount(ctx, &accumulate.Params{URL: account.URL})
+	if err != nil {
+		return err
+	}
+
+	if store.TrimScheme(balance.Data.TokenURL) != store.TrimScheme(i.Config.ACME.TokenIssuer) {
+		return fmt.Errorf("account holds %s, not ACME", balance.Data.TokenURL)
+	}
+
+	account.Balance, err = schema.ParseAmount(balance.Data.Balance)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	account.UpdatedAt = &now
+
+	return nil
+
+}
+
+// searchLockedAccount searches locked account by URL (case insensitive)
+func searchLockedAccount(accounts []*schema.LockedAccount, url string) *schema.LockedAccount {
+
+	for _, a := range accounts {
+		if store.TrimScheme(a.URL) == store.TrimScheme(url) {
+			return a
+		}
+	}
+
+	return nil
+
+}
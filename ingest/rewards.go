@@ -0,0 +1,191 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/labstack/gommon/log"
+)
+
+// rewardsJob is a rewards account to scan, rewards are attributed to the first staker using the account
+type rewardsJob struct {
+	identity string
+	account  string
+	// backfill is set if the account was never scanned
+	backfill bool
+}
+
+// fetchRewards scans tx history of stakers' rewards accounts for new payouts using a bounded pool of workers.
+// Cursors hold number of scanned transactions of every account and are advanced after every stored page,
+// so a failed account is scanned from the same position during the next cycle.
+// Canceling ctx stops sending new requests.
+func (i *Ingestor) fetchRewards(ctx context.Context, records []*schema.StakingRecord, cursors map[string]int64, errs *errorList) (*schema.RequestStats, int64) {
+
+	jobs := []*rewardsJob{}
+	seen := make(map[string]bool)
+
+	for _, record := range records {
+		key := strings.ToLower(record.Rewards)
+		if record.Rewards == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		jobs = append(jobs, &rewardsJob{identity: record.Identity, account: record.Rewards})
+	}
+
+	stats := &schema.RequestStats{Requested: int64(len(jobs))}
+	added := int64(0)
+
+	queue := make(chan *rewardsJob)
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	for w := 0; w < i.Config.Ingest.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+
+				key := strings.ToLower(job.account)
+
+				mu.Lock()
+				cursor, scanned := cursors[key]
+				mu.Unlock()
+
+				job.backfill = !scanned
+
+				cursor, n, err := i.fetchAccountRewards(ctx, job, cursor)
+				atomic.AddInt64(&added, int64(n))
+
+				// an interrupted first scan starts over, so the rest of the history is not taken for new payouts
+				if !job.backfill || err == nil {
+					mu.Lock()
+					cursors[key] = cursor
+					mu.Unlock()
+				}
+
+				if err != nil {
+					err = fmt.Errorf("can not fetch rewards of %s: %s", job.account, err)
+					log.Error(err)
+					errs.add(err)
+					atomic.AddInt64(&stats.Failed, 1)
+					continue
+				}
+				atomic.AddInt64(&stats.Succeeded, 1)
+
+			}
+		}()
+	}
+
+feed:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return stats, added
+
+}
+
+// fetchAccountRewards pages through tx history of rewards account from cursor, stores found payouts
+// and returns the new cursor with the number of added rewards. A missing account has no payouts yet.
+func (i *Ingestor) fetchAccountRewards(ctx context.Context, job *rewardsJob, cursor int64) (int64, int, error) {
+
+	added := 0
+
+	cursor, err := i.scanTxHistory(ctx, job.account, cursor, func(txs []*accumulate.QueryTokenTxResponse) error {
+
+		rewards := []*schema.Reward{}
+		now := time.Now()
+
+		for _, tx := range txs {
+
+			amount, err := i.payoutAmount(job.account, tx)
+			if err != nil {
+				log.Error("can not parse payout ", tx.TxID, ": ", err)
+				continue
+			}
+			if amount.Sign() == 0 {
+				continue
+			}
+
+			rewards = append(rewards, &schema.Reward{
+				Identity:  job.identity,
+				Account:   job.account,
+				TxID:      tx.TxID,
+				TxHash:    tx.TxHash,
+				Amount:    amount,
+				FirstSeen: now,
+				Backfill:  job.backfill,
+			})
+
+		}
+
+		n, err := i.Store.AppendRewards(rewards...)
+		if err != nil {
+			return err
+		}
+		added += n
+
+		return nil
+
+	})
+
+	return cursor, added, err
+
+}
+
+// payoutAmount returns ACME amount paid to account by the staking payout account in tx, 0 if tx is not a payout
+func (i *Ingestor) payoutAmount(account string, tx *accumulate.QueryTokenTxResponse) (schema.Amount, error) {
+
+	if tx.Data == nil {
+		return schema.Amount{}, nil
+	}
+
+	payout := store.TrimScheme(i.Config.Staking.PayoutAccount)
+
+	switch tx.Type {
+	case "syntheticDepositTokens":
+		// source is the principal of the cause, older nodes only return the cause (acc://<hash>@<principal>)
+		source := tx.Data.Source
+		if source == "" {
+			_, source, _ = strings.Cut(tx.Data.Cause, "@")
+		}
+		if tx.Data.IsRefund || store.TrimScheme(source) != payout || store.TrimScheme(tx.Data.Token) != store.TrimScheme(i.Config.ACME.TokenIssuer) {
+			return schema.Amount{}, nil
+		}
+		return schema.ParseAmount(tx.Data.Amount)
+	case "sendTokens":
+		total := schema.Amount{}
+		if store.TrimScheme(tx.Data.From) != payout {
+			return total, nil
+		}
+		for _, to := range tx.Data.To {
+			if store.TrimScheme(to.URL) != store.TrimScheme(account) {
+				continue
+			}
+			amount, err := schema.ParseAmount(to.Amount)
+			if err != nil {
+				return schema.Amount{}, err
+			}
+			total = total.Add(amount)
+		}
+		return total, nil
+	}
+
+	return schema.Amount{}, nil
+
+}
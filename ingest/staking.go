@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/accumulate"
+	"github.com/AccumulateNetwork/metrics-api/metrics"
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/jinzhu/copier"
+	"github.com/labstack/gommon/log"
+)
+
+// fetchStakingRecords pages through staking data entries starting from cursor, applies them to records
+// and returns updated records and cursor. The cursor advances after every processed page,
+// so a failed request is retried from the same page during the next cycle.
+func (i *Ingestor) fetchStakingRecords(ctx context.Context, records []*schema.StakingRecord, cursor int64) ([]*schema.StakingRecord, int64, error) {
+
+	for {
+
+		stakingData, err := i.Client.QueryDataSet(ctx, &accumulate.Params{URL: i.Config.Staking.DataAccount, Count: i.Config.Staking.PageSize, Start: cursor, Expand: true})
+		if err != nil {
+			return records, cursor, err
+		}
+
+		log.Info("received ", len(stakingData.Items), " data entries from ", i.Config.Staking.DataAccount, " starting at ", cursor, " (total ", stakingData.Total, ")")
+
+		versions := []*schema.StakingRecordVersion{}
+
+		for n, entry := range stakingData.Items {
+
+			var version *schema.StakingRecordVersion
+			records, version = applyStakingEntry(records, entry, cursor+int64(n))
+
+			if version != nil {
+				versions = append(versions, version)
+			}
+
+		}
+
+		added, err := i.Store.AppendStakingHistory(versions...)
+		if err != nil {
+			return records, cursor, err
+		}
+
+		log.Debug("recorded ", added, " new staking record versions")
+
+		cursor += int64(len(stakingData.Items))
+
+		if len(stakingData.Items) == 0 || cursor >= stakingData.Total {
+			return records, cursor, nil
+		}
+
+	}
+
+}
+
+// applyStakingEntry parses staking data entry, adds or updates staking record
+// and returns updated records with the parsed version of the record
+func applyStakingEntry(records []*schema.StakingRecord, entry *accumulate.DataEntry, chainIndex int64) ([]*schema.StakingRecord, *schema.StakingRecordVersion) {
+
+	if len(entry.Entry.Data) == 0 {
+		log.Error("empty data entry ", entry.EntryHash)
+		metrics.ParseFailures.Inc()
+		return records, nil
+	}
+
+	entryData, err := hex.DecodeString(entry.Entry.Data[0])
+	if err != nil {
+		log.Error(err)
+		metrics.ParseFailures.Inc()
+		return records, nil
+	}
+
+	stRecord, err := schema.ParseStakingRecord(entryData)
+	if err != nil {
+		log.Error(err)
+		metrics.ParseFailures.Inc()
+		return records, nil
+	}
+
+	// fill entry hash
+	stRecord.EntryHash = entry.EntryHash
+
+	// keep every version of the record
+	version := &schema.StakingRecordVersion{ChainIndex: chainIndex, FirstSeen: time.Now()}
+	copier.Copy(version, stRecord)
+
+	// check if record with this identity already exists
+	exists := store.SearchStakingRecordByIdentity(records, stRecord.Identity)
+
+	// if not found, append new record
+	if exists == nil {
+		log.Debug("added staking record for: ", stRecord.Identity)
+		return append(records, stRecord), version
+	}
+
+	log.Debug("updated staking record for: ", stRecord.Identity)
+
+	// keep known balance until it is refreshed
+	stRecord.Balance = exists.Balance
+	*exists = *stRecord
+
+	return records, version
+
+}
@@ -1,138 +1,133 @@
 package main
 
 import (
-	"encoding/hex"
-	"strconv"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/AccumulateNetwork/metrics-api/accumulate"
 	"github.com/AccumulateNetwork/metrics-api/api"
-	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/config"
+	"github.com/AccumulateNetwork/metrics-api/ingest"
+	"github.com/AccumulateNetwork/metrics-api/metrics"
 	"github.com/AccumulateNetwork/metrics-api/store"
-	"github.com/jinzhu/copier"
 	"github.com/labstack/gommon/log"
 )
 
-const ACCUMULATE_API = "https://mainnet.accumulatenetwork.io/v2"
-const ACCUMULATE_CLIENT_TIMEOUT = 5
-const API_PORT = 8082
-const ACME_TOKEN_ISSUER = "acc://acme"
-const STAKING_DATA_ACCOUNT = "acc://staking.acme/registered"
-const STAKING_PAGESIZE = 10000
-
 func main() {
-
-	store.StakingRecords = &schema.StakingRecords{}
-
-	client := accumulate.NewAccumulateClient(ACCUMULATE_API, ACCUMULATE_CLIENT_TIMEOUT)
-
-	die := make(chan bool)
-	go getStats(client, die)
-
-	log.Fatal(api.StartAPI(API_PORT))
+	os.Exit(run())
 }
 
-func getStats(client *accumulate.AccumulateClient, die chan bool) {
-
-	for {
-
-		select {
-		default:
-
-			acme := &schema.ACME{}
-
-			acmeData, err := client.QueryToken(&accumulate.Params{URL: ACME_TOKEN_ISSUER})
-			if err != nil {
-				log.Error(err)
-			}
-
-			copier.Copy(&acme, acmeData.Data)
-
-			acme.Total, err = strconv.ParseInt(acmeData.Data.Issued, 10, 64)
-			if err != nil {
-				log.Error(err)
-			}
-
-			acme.Max, err = strconv.ParseInt(acmeData.Data.SupplyLimit, 10, 64)
-			if err != nil {
-				log.Error(err)
-			}
-
-			store.ACME = acme
+// run starts the service and blocks until SIGINT/SIGTERM or a server failure, it returns process exit code
+func run() int {
 
-			stakingData, err := client.QueryDataSet(&accumulate.Params{URL: STAKING_DATA_ACCOUNT, Count: STAKING_PAGESIZE, Start: 0, Expand: true})
-			if err != nil {
-				log.Error(err)
-			}
-
-			log.Info("received ", len(stakingData.Items), " data entries from ", STAKING_DATA_ACCOUNT)
-
-			// parse staking data entries
-			for _, entry := range stakingData.Items {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Error(err)
+		return 2
+	}
 
-				entryData, err := hex.DecodeString(entry.Entry.Data[0])
-				if err != nil {
-					log.Error(err)
-					continue
-				}
+	st, err := store.NewStore(&cfg.Store)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
 
-				stRecord, err := schema.ParseStakingRecord(entryData)
-				if err != nil {
-					log.Error(err)
-					continue
-				}
+	log.Info("using ", cfg.Store.Driver, " store")
 
-				// fill entry hash
-				stRecord.EntryHash = entry.EntryHash
+	// export metrics of the stored snapshot until the first cycle completes
+	metrics.ObserveSnapshot(st.Snapshot())
 
-				// check if record with this identity already exists
-				exists := store.SearchStakingRecordByIdentity(stRecord.Identity)
+	rpc := accumulate.NewAccumulateClient(cfg.Accumulate.Endpoints, time.Duration(cfg.Accumulate.Timeout))
+	rpc.SetRateLimit(cfg.Accumulate.RateLimit)
 
-				// if not found, append new record
-				if exists == nil {
-					log.Debug("added staking record for: ", stRecord.Identity)
-					store.StakingRecords.Items = append(store.StakingRecords.Items, stRecord)
-					continue
-				}
+	retry := cfg.Accumulate.Retry
+	rpc.Retry = &accumulate.RetryPolicy{
+		MaxAttempts:    retry.MaxAttempts,
+		InitialBackoff: time.Duration(retry.InitialBackoff),
+		MaxBackoff:     time.Duration(retry.MaxBackoff),
+		Multiplier:     retry.Multiplier,
+		Jitter:         retry.Jitter,
+	}
 
-				log.Debug("updated staking record for: ", stRecord.Identity)
-				*exists = *stRecord
+	rpc.SetCircuitBreaker(cfg.Accumulate.CircuitBreaker.FailureThreshold, time.Duration(cfg.Accumulate.CircuitBreaker.Cooldown))
 
-			}
+	var client accumulate.Client = rpc
+	if cfg.Accumulate.APIVersion == accumulate.APIv3 {
+		client = accumulate.NewAccumulateV3Client(rpc)
+	}
 
-			log.Info("total staking records: ", len(store.StakingRecords.Items))
+	log.Info("using Accumulate ", cfg.Accumulate.APIVersion, " API")
 
-			snapshot := &schema.StakingRecords{}
-			copier.Copy(&snapshot.Items, store.StakingRecords.Items)
+	ingestor := ingest.NewIngestor(cfg, client, st)
 
-			// get ACME balances of stakers
-			for _, record := range snapshot.Items {
+	// SIGINT and SIGTERM cancel the root context
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-				balance, err := client.QueryTokenAccount(&accumulate.Params{URL: record.Stake})
-				if err != nil {
-					log.Error(err)
-					continue
-				}
+	client.StartHealthChecks(ctx, time.Duration(cfg.Accumulate.HealthCheckInterval))
 
-				record.Balance, err = strconv.ParseInt(balance.Data.Balance, 10, 64)
-				if err != nil {
-					log.Error(err)
-					continue
-				}
+	// SIGHUP triggers full rescan of the staking data account
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			ingestor.Rescan()
+		}
+	}()
+
+	ingestionDone := make(chan struct{})
+	go func() {
+		ingestor.Run(ctx)
+		close(ingestionDone)
+	}()
+
+	server := api.NewAPI(cfg, st, client)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start()
+	}()
+
+	code := 0
+
+	select {
+	case <-ctx.Done():
+		log.Info("shutting down")
+	case err = <-serverErr:
+		log.Error("api server stopped: ", err)
+		code = 1
+		stop()
+	}
 
-			}
+	// drain in-flight requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.API.ShutdownTimeout))
+	defer cancel()
 
-			copier.Copy(&store.StakingRecords.Items, snapshot.Items)
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		log.Error("api server shutdown: ", err)
+		code = 1
+	}
 
-			now := time.Now()
-			store.UpdatedAt = &now
+	// wait for the ingestion loop to stop
+	select {
+	case <-ingestionDone:
+	case <-shutdownCtx.Done():
+		log.Error("ingestion did not stop in ", cfg.API.ShutdownTimeout)
+		code = 1
+	}
 
-			time.Sleep(time.Duration(10) * time.Minute)
+	// flush persistent store
+	if err = st.Close(); err != nil {
+		log.Error("store close: ", err)
+		code = 1
+	}
 
-		case <-die:
-			return
-		}
+	log.Info("stopped")
 
-	}
+	return code
 
 }
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry holds every exported metric
+var Registry = prometheus.NewRegistry()
+
+// network metrics, updated from the latest snapshot
+var (
+	Supply = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "accumulate_acme_supply_tokens",
+		Help: "ACME supply in tokens by kind (total, max, staked, locked, circulating).",
+	}, []string{"kind"})
+
+	Stakers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "accumulate_stakers",
+		Help: "Number of stakers by staking type.",
+	}, []string{"type"})
+
+	Staked = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "accumulate_staked_tokens",
+		Help: "Staked ACME in tokens by staking type.",
+	}, []string{"type"})
+
+	SnapshotID = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "metrics_api_snapshot_id",
+		Help: "ID of the latest ingestion snapshot.",
+	})
+
+	UpdatedAt = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "metrics_api_snapshot_updated_timestamp_seconds",
+		Help: "Unix time of the last successful ingestion cycle.",
+	})
+)
+
+// ingestion metrics
+var (
+	CycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "metrics_api_ingestion_cycle_duration_seconds",
+		Help:    "Duration of ingestion cycles.",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	})
+
+	Cycles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "metrics_api_ingestion_cycles_total",
+		Help: "Number of ingestion cycles by result (success, failure).",
+	}, []string{"result"})
+
+	RPCCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "metrics_api_rpc_calls_total",
+		Help: "Number of Accumulate API calls by JSON-RPC method, including retries and failovers.",
+	}, []string{"method"})
+
+	RPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "metrics_api_rpc_errors_total",
+		Help: "Number of failed Accumulate API calls by JSON-RPC method.",
+	}, []string{"method"})
+
+	ParseFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "metrics_api_staking_entry_parse_failures_total",
+		Help: "Number of staking data entries that could not be parsed.",
+	})
+
+	BalanceRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "metrics_api_balance_requests_total",
+		Help: "Number of staker balance requests by result (success, failure).",
+	}, []string{"result"})
+
+	RewardPayouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "metrics_api_reward_payouts_total",
+		Help: "Number of new staking reward payouts found by ingestion.",
+	})
+)
+
+// HTTP metrics
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "metrics_api_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+)
+
+func init() {
+
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Supply, Stakers, Staked, SnapshotID, UpdatedAt,
+		CycleDuration, Cycles, RPCCalls, RPCErrors, ParseFailures, BalanceRequests, RewardPayouts,
+		HTTPRequestDuration,
+	)
+
+}
+
+// ObserveSnapshot updates network metrics from snapshot
+func ObserveSnapshot(snapshot *schema.Snapshot) {
+
+	if snapshot == nil {
+		return
+	}
+
+	SnapshotID.Set(float64(snapshot.ID))
+
+	if !snapshot.UpdatedAt.IsZero() {
+		UpdatedAt.Set(float64(snapshot.UpdatedAt.Unix()))
+	}
+
+	if snapshot.ACME == nil {
+		return
+	}
+
+	precision := snapshot.ACME.Precision
+	staked := store.GetTotalStake(snapshot.StakingRecords)
+
+	Supply.WithLabelValues("total").Set(Tokens(snapshot.ACME.Total, precision))
+	Supply.WithLabelValues("max").Set(Tokens(snapshot.ACME.Max, precision))
+	Supply.WithLabelValues("staked").Set(Tokens(staked, precision))
+	Supply.WithLabelValues("locked").Set(Tokens(store.GetLockedSupply(snapshot.LockedAccounts), precision))
+	Supply.WithLabelValues("circulating").Set(Tokens(store.GetCirculatingSupply(snapshot), precision))
+
+	validators := store.GetValidatorsNumber(snapshot.StakingRecords)
+	Stakers.WithLabelValues("coreValidator").Set(float64(validators.CoreValidator))
+	Stakers.WithLabelValues("coreFollower").Set(float64(validators.CoreFollower))
+	Stakers.WithLabelValues("stakingValidator").Set(float64(validators.StakingValidator))
+	Stakers.WithLabelValues("delegated").Set(float64(validators.Delegated))
+	Stakers.WithLabelValues("pure").Set(float64(validators.Pure))
+
+	Staked.Reset()
+	for stakingType, amount := range store.GetStakeByType(snapshot.StakingRecords) {
+		Staked.WithLabelValues(stakingType).Set(Tokens(amount, precision))
+	}
+
+}
+
+// ObserveCycle updates ingestion metrics from cycle stats
+func ObserveCycle(stats *schema.CycleStats) {
+
+	CycleDuration.Observe(stats.Duration)
+
+	if stats.Success {
+		Cycles.WithLabelValues("success").Inc()
+	} else {
+		Cycles.WithLabelValues("failure").Inc()
+	}
+
+	if stats.Balances != nil {
+		BalanceRequests.WithLabelValues("success").Add(float64(stats.Balances.Succeeded))
+		BalanceRequests.WithLabelValues("failure").Add(float64(stats.Balances.Failed))
+	}
+
+	RewardPayouts.Add(float64(stats.NewRewards))
+
+}
+
+// Tokens converts raw amount into tokens, gauges are float64 anyway
+func Tokens(amount schema.Amount, precision int64) float64 {
+
+	return amount.Float64(precision)
+
+}
@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Amount is an arbitrary-precision token amount in base units, serialized as a decimal string.
+// Amounts are immutable, so they are safe to copy and share between snapshots; the zero value is 0.
+type Amount struct {
+	i *big.Int
+}
+
+// NewAmount returns amount of n base units
+func NewAmount(n int64) Amount {
+	return Amount{i: big.NewInt(n)}
+}
+
+// ParseAmount parses decimal integer amount of base units
+func ParseAmount(s string) (Amount, error) {
+
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount '%s'", s)
+	}
+
+	return Amount{i: i}, nil
+
+}
+
+// Int returns copy of amount as big.Int
+func (a Amount) Int() *big.Int {
+
+	if a.i == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(a.i)
+
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{i: new(big.Int).Add(a.Int(), b.Int())}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{i: new(big.Int).Sub(a.Int(), b.Int())}
+}
+
+// Cmp compares amounts and returns -1, 0 or +1
+func (a Amount) Cmp(b Amount) int {
+	return a.Int().Cmp(b.Int())
+}
+
+func (a Amount) Sign() int {
+	return a.Int().Sign()
+}
+
+func (a Amount) String() string {
+	return a.Int().String()
+}
+
+// Ratio returns a/b as float64, or 0 if b is 0
+func (a Amount) Ratio(b Amount) float64 {
+
+	if b.Sign() == 0 {
+		return 0
+	}
+
+	f, _ := new(big.Rat).SetFrac(a.Int(), b.Int()).Float64()
+
+	return f
+
+}
+
+// Float64 returns amount in tokens of precision as float64, use it only where precision loss is acceptable
+func (a Amount) Float64(precision int64) float64 {
+	return a.Ratio(Amount{i: new(big.Int).Exp(big.NewInt(10), big.NewInt(precision), nil)})
+}
+
+// Tokens returns exact amount in tokens of precision as decimal string, e.g. "210914735.19485401"
+func (a Amount) Tokens(precision int64) string {
+
+	s := new(big.Int).Abs(a.Int()).String()
+
+	sign := ""
+	if a.Sign() < 0 {
+		sign = "-"
+	}
+
+	if precision <= 0 {
+		return sign + s
+	}
+
+	if n := int(precision) + 1 - len(s); n > 0 {
+		s = strings.Repeat("0", n) + s
+	}
+
+	return sign + s[:len(s)-int(precision)] + "." + s[len(s)-int(precision):]
+
+}
+
+// TokensRounded returns amount in tokens of precision rounded half away from zero to decimals, e.g. "210914735.19"
+func (a Amount) TokensRounded(precision int64, decimals int) string {
+
+	shift := precision - int64(decimals)
+
+	if shift <= 0 {
+		s := a.Tokens(precision)
+		if shift < 0 && precision == 0 {
+			s += "."
+		}
+		return s + strings.Repeat("0", int(-shift))
+	}
+
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(shift), nil)
+	half := new(big.Int).Div(unit, big.NewInt(2))
+
+	q := new(big.Int).Abs(a.Int())
+	q.Add(q, half).Quo(q, unit)
+	if a.Sign() < 0 {
+		q.Neg(q)
+	}
+
+	return Amount{i: q}.Tokens(int64(decimals))
+
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts decimal strings and JSON numbers, which older stored snapshots hold
+func (a *Amount) UnmarshalJSON(data []byte) error {
+
+	s := string(bytes.Trim(data, `"`))
+	if s == "null" {
+		*a = Amount{}
+		return nil
+	}
+
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+
+	return nil
+
+}
@@ -0,0 +1,67 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+)
+
+func TestAmountTokens(t *testing.T) {
+
+	tests := []struct {
+		amount    string
+		precision int64
+		decimals  int
+		exact     string
+		rounded   string
+	}{
+		{"21091473519485401", 8, 2, "210914735.19485401", "210914735.19"},
+		{"21091473519985401", 8, 0, "210914735.19985401", "210914735"},
+		{"21091473569985401", 8, 0, "210914735.69985401", "210914736"},
+		{"5", 8, 4, "0.00000005", "0.0000"},
+		{"-150", 2, 0, "-1.50", "-2"},
+		{"123", 0, 2, "123", "123.00"},
+		{"1200", 2, 4, "12.00", "12.0000"},
+		// exceeds int64
+		{"123456789012345678901234567890", 18, 3, "123456789012.345678901234567890", "123456789012.346"},
+	}
+
+	for _, test := range tests {
+		amount, err := schema.ParseAmount(test.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := amount.Tokens(test.precision); s != test.exact {
+			t.Errorf("expected %s in tokens to be %s, got %s", test.amount, test.exact, s)
+		}
+		if s := amount.TokensRounded(test.precision, test.decimals); s != test.rounded {
+			t.Errorf("expected %s rounded to %d decimals to be %s, got %s", test.amount, test.decimals, test.rounded, s)
+		}
+	}
+
+	if _, err := schema.ParseAmount("1.5"); err == nil {
+		t.Error("expected error for fractional base units")
+	}
+
+}
+
+func TestAmountJSON(t *testing.T) {
+
+	var v struct {
+		A schema.Amount `json:"a"`
+		B schema.Amount `json:"b"`
+		C schema.Amount `json:"c"`
+	}
+
+	// numbers are accepted for snapshots stored before amounts became strings
+	if err := json.Unmarshal([]byte(`{"a":"123456789012345678901234567890","b":30000000000000000}`), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(v)
+	if string(data) != `{"a":"123456789012345678901234567890","b":"30000000000000000","c":"0"}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+
+}
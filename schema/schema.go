@@ -1,5 +1,7 @@
 package schema
 
+import "time"
+
 type StakingRecord struct {
 	Type               string `json:"type" validate:"required"`
 	Status             string `json:"status"`
@@ -9,7 +11,22 @@ type StakingRecord struct {
 	Delegate           string `json:"delegate"`
 	AcceptingDelegates string `json:"acceptingDelegates"`
 	EntryHash          string `json:"entryHash"`
-	Balance            int64  `json:"balance"`
+	Balance            Amount `json:"balance"`
+}
+
+// StakingRecordVersion is a staking record as registered by a single data entry
+type StakingRecordVersion struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Identity           string    `json:"identity"`
+	Stake              string    `json:"stake"`
+	Rewards            string    `json:"rewards"`
+	Delegate           string    `json:"delegate"`
+	AcceptingDelegates string    `json:"acceptingDelegates"`
+	EntryHash          string    `json:"entryHash"`
+	ChainIndex         int64     `json:"chainIndex"`
+	FirstSeen          time.Time `json:"firstSeen"`
+	Changes            []string  `json:"changes"`
 }
 
 type StakingRecords struct {
@@ -19,8 +36,155 @@ type StakingRecords struct {
 type ACME struct {
 	Symbol    string `json:"symbol"`
 	Precision int64  `json:"precision"`
-	Total     int64  `json:"total"`
-	Max       int64  `json:"max"`
+	Total     Amount `json:"total"`
+	Max       Amount `json:"max"`
+}
+
+// Token is supply of a token issuer
+type Token struct {
+	URL       string `json:"url"`
+	Symbol    string `json:"symbol"`
+	Precision int64  `json:"precision"`
+	Total     Amount `json:"total"`
+	Max       Amount `json:"max"`
+}
+
+// SupplyPoint is ACME supply recorded by a successful ingestion cycle
+type SupplyPoint struct {
+	Time        time.Time `json:"time"`
+	SnapshotID  int64     `json:"snapshotId"`
+	Total       Amount    `json:"total"`
+	Max         Amount    `json:"max"`
+	Staked      Amount    `json:"staked"`
+	Locked      Amount    `json:"locked"`
+	Circulating Amount    `json:"circulating"`
+}
+
+// SupplyCandle aggregates supply points of a time interval
+type SupplyCandle struct {
+	Time        time.Time `json:"time"`
+	Points      int       `json:"points"`
+	Total       *OHLC     `json:"total"`
+	Max         *OHLC     `json:"max"`
+	Staked      *OHLC     `json:"staked"`
+	Locked      *OHLC     `json:"locked"`
+	Circulating *OHLC     `json:"circulating"`
+}
+
+// OHLC holds the first, last, min and max values of an interval
+type OHLC struct {
+	Open  Amount `json:"open"`
+	Close Amount `json:"close"`
+	Min   Amount `json:"min"`
+	Max   Amount `json:"max"`
+}
+
+// Reward is an ACME payout from the staking payout account received by the rewards account of a staker.
+// Transactions carry no timestamps, so FirstSeen is the time ingestion found the payout.
+type Reward struct {
+	Identity  string    `json:"identity"`
+	Account   string    `json:"account"`
+	TxID      string    `json:"txid"`
+	TxHash    string    `json:"txHash"`
+	Amount    Amount    `json:"amount"`
+	FirstSeen time.Time `json:"firstSeen"`
+	// Backfill is set for payouts found by the first scan of the account, which were paid at unknown time
+	Backfill bool `json:"backfill"`
+}
+
+// RewardsPeriod sums rewards first seen within a time interval
+type RewardsPeriod struct {
+	Time    time.Time `json:"time"`
+	Payouts int64     `json:"payouts"`
+	Amount  Amount    `json:"amount"`
+}
+
+// Yield is realised staking yield over a trailing window
+type Yield struct {
+	// Window is the window in days, only windows covered by tracked payouts are reported, so Days equals Window
+	Window  int     `json:"window"`
+	Days    float64 `json:"days"`
+	Rewards Amount  `json:"rewards"`
+	Stake   Amount  `json:"stake"`
+	APR     float64 `json:"apr"`
+	APY     float64 `json:"apy"`
+}
+
+// SupplyFlow sums changes of ACME issued supply within a UTC day, or a longer interval when grouped
+type SupplyFlow struct {
+	Time time.Time `json:"time"`
+	// Issued is ACME added to supply, that is the net change plus burned ACME
+	Issued Amount `json:"issued"`
+	// Burned is ACME burned by burn transactions of the issuer history, e.g. when credits are purchased
+	Burned Amount `json:"burned"`
+	// NetChange is the change of issued supply between ingestion cycles
+	NetChange Amount `json:"netChange"`
+	Burns     int64  `json:"burns"`
+}
+
+// LockedAccount is a configured ACME token account whose balance is excluded from circulating supply
+type LockedAccount struct {
+	Label   string `json:"label"`
+	URL     string `json:"url"`
+	Balance Amount `json:"balance"`
+	// Staked is set for staking accounts of stakers, their balance is counted as staked, not locked
+	Staked bool `json:"staked"`
+	// UpdatedAt is time of the last successful balance request, null if it never succeeded
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// Snapshot is the complete result of an ingestion cycle, it must not be modified once saved
+type Snapshot struct {
+	ID   int64 `json:"id"`
+	ACME *ACME `json:"acme"`
+	// Tokens holds supply of every tracked token issuer, ACME first
+	Tokens         []*Token         `json:"tokens"`
+	StakingRecords []*StakingRecord `json:"stakingRecords"`
+	StakingCursor  int64            `json:"stakingCursor"`
+	// RewardsCursors holds number of scanned transactions of every rewards account (lowercase URL)
+	RewardsCursors map[string]int64 `json:"rewardsCursors"`
+	LockedAccounts []*LockedAccount `json:"lockedAccounts"`
+	// BurnsCursor holds number of scanned transactions of ACME issuer history, null if it was never scanned
+	BurnsCursor *int64      `json:"burnsCursor"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+	Stats       *CycleStats `json:"stats"`
+}
+
+// CycleStats describes an ingestion cycle
+type CycleStats struct {
+	StartedAt   time.Time     `json:"startedAt"`
+	Duration    float64       `json:"duration"`
+	Concurrency int           `json:"concurrency"`
+	Balances    *RequestStats `json:"balances"`
+	// Rewards counts rewards accounts scanned for new payouts
+	Rewards    *RequestStats `json:"rewards"`
+	NewRewards int64         `json:"newRewards"`
+	// Endpoint served most calls of the cycle, Endpoints holds number of calls served by each endpoint
+	Endpoint  string           `json:"endpoint"`
+	Endpoints map[string]int64 `json:"endpoints"`
+	// Success is set if ACME supply and staking entries were fetched
+	Success     bool     `json:"success"`
+	Errors      []string `json:"errors"`
+	ErrorsTotal int      `json:"errorsTotal"`
+}
+
+type RequestStats struct {
+	Requested int64 `json:"requested"`
+	Succeeded int64 `json:"succeeded"`
+	Failed    int64 `json:"failed"`
+}
+
+// Validator is a core or staking validator with the stake delegated to it
+type Validator struct {
+	Identity           string `json:"identity"`
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	AcceptingDelegates string `json:"acceptingDelegates"`
+	// Balance is the validator's own stake
+	Balance    Amount `json:"balance"`
+	Delegated  Amount `json:"delegated"`
+	Delegators int64  `json:"delegators"`
+	TotalStake Amount `json:"totalStake"`
 }
 
 type ValidatorsNumber struct {
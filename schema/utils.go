@@ -26,3 +26,49 @@ func ParseStakingRecord(entry []byte) (*StakingRecord, error) {
 	return res, nil
 
 }
+
+// DiffStakingRecords returns JSON names of staking record fields changed between two versions
+func DiffStakingRecords(prev, next *StakingRecordVersion) []string {
+
+	changes := []string{}
+
+	if prev == nil {
+		return changes
+	}
+
+	if prev.Type != next.Type {
+		changes = append(changes, "type")
+	}
+	if prev.Status != next.Status {
+		changes = append(changes, "status")
+	}
+	if prev.Stake != next.Stake {
+		changes = append(changes, "stake")
+	}
+	if prev.Rewards != next.Rewards {
+		changes = append(changes, "rewards")
+	}
+	if prev.Delegate != next.Delegate {
+		changes = append(changes, "delegate")
+	}
+	if prev.AcceptingDelegates != next.AcceptingDelegates {
+		changes = append(changes, "acceptingDelegates")
+	}
+
+	return changes
+
+}
+
+// Add records the next value of the interval
+func (o *OHLC) Add(value Amount) {
+
+	o.Close = value
+
+	if value.Cmp(o.Min) < 0 {
+		o.Min = value
+	}
+	if value.Cmp(o.Max) > 0 {
+		o.Max = value
+	}
+
+}
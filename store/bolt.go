@@ -0,0 +1,274 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketSnapshot = []byte("snapshot")
+var bucketHistory = []byte("history")
+var bucketSupply = []byte("supply")
+var bucketRewards = []byte("rewards")
+var bucketFlows = []byte("flows")
+
+var keyLatest = []byte("latest")
+
+// BoltStore persists metrics in embedded bbolt database.
+// All data is loaded into memory at boot, reads are served from memory and writes go to disk first.
+type BoltStore struct {
+	*MemoryStore
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) bbolt database and loads stored metrics
+func NewBoltStore(path string) (*BoltStore, error) {
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("can not open bolt database %s: %s", path, err)
+	}
+
+	s := &BoltStore{MemoryStore: NewMemoryStore(), db: db}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketSnapshot, bucketHistory, bucketSupply, bucketRewards, bucketFlows} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can not init bolt database %s: %s", path, err)
+	}
+
+	if err = s.load(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can not load bolt database %s: %s", path, err)
+	}
+
+	return s, nil
+
+}
+
+// load reads stored metrics into memory
+func (s *BoltStore) load() error {
+
+	return s.db.View(func(tx *bolt.Tx) error {
+
+		var snapshot *schema.Snapshot
+		if err := getJSON(tx.Bucket(bucketSnapshot), keyLatest, &snapshot); err != nil {
+			return err
+		}
+		if snapshot != nil {
+			s.MemoryStore.SaveSnapshot(snapshot)
+		}
+
+		err := tx.Bucket(bucketHistory).ForEach(func(k, v []byte) error {
+			var history []*schema.StakingRecordVersion
+			if err := json.Unmarshal(v, &history); err != nil {
+				return err
+			}
+			s.stakingHistory[string(k)] = history
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// keys are big-endian timestamps, so points are iterated in time order
+		err = tx.Bucket(bucketSupply).ForEach(func(k, v []byte) error {
+			point := &schema.SupplyPoint{}
+			if err := json.Unmarshal(v, point); err != nil {
+				return err
+			}
+			s.supplyHistory = append(s.supplyHistory, point)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		rewards := []*schema.Reward{}
+		err = tx.Bucket(bucketRewards).ForEach(func(k, v []byte) error {
+			reward := &schema.Reward{}
+			if err := json.Unmarshal(v, reward); err != nil {
+				return err
+			}
+			rewards = append(rewards, reward)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = s.MemoryStore.AppendRewards(rewards...)
+		if err != nil {
+			return err
+		}
+
+		// keys are big-endian day timestamps, so days are iterated in time order
+		return tx.Bucket(bucketFlows).ForEach(func(k, v []byte) error {
+			flow := &schema.SupplyFlow{}
+			if err := json.Unmarshal(v, flow); err != nil {
+				return err
+			}
+			s.supplyFlows = append(s.supplyFlows, flow)
+			return nil
+		})
+
+	})
+
+}
+
+func (s *BoltStore) SaveSnapshot(snapshot *schema.Snapshot) error {
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketSnapshot), keyLatest, snapshot)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.MemoryStore.SaveSnapshot(snapshot)
+
+}
+
+func (s *BoltStore) AppendStakingHistory(versions ...*schema.StakingRecordVersion) (int, error) {
+
+	added, err := s.MemoryStore.AppendStakingHistory(versions...)
+	if err != nil || added == 0 {
+		return added, err
+	}
+
+	// persist full history of every touched identity
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		history := tx.Bucket(bucketHistory)
+		for _, version := range versions {
+			key := strings.ToLower(version.Identity)
+			if err := putJSON(history, []byte(key), s.stakingHistory[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return added, err
+
+}
+
+func (s *BoltStore) AppendSupplyPoint(point *schema.SupplyPoint) error {
+
+	s.mu.RLock()
+	if n := len(s.supplyHistory); n > 0 && !point.Time.After(s.supplyHistory[n-1].Time) {
+		s.mu.RUnlock()
+		return fmt.Errorf("supply point at %s is not newer than the last one at %s", point.Time, s.supplyHistory[n-1].Time)
+	}
+	s.mu.RUnlock()
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(point.Time.UnixNano()))
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketSupply), key, point)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.MemoryStore.AppendSupplyPoint(point)
+
+}
+
+func (s *BoltStore) AppendRewards(rewards ...*schema.Reward) (int, error) {
+
+	s.mu.RLock()
+	rewards = s.newRewards(rewards)
+	s.mu.RUnlock()
+
+	if len(rewards) == 0 {
+		return 0, nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketRewards)
+		for _, reward := range rewards {
+			if err := putJSON(bucket, []byte(rewardKey(reward)), reward); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return s.MemoryStore.AppendRewards(rewards...)
+
+}
+
+func (s *BoltStore) AddSupplyFlow(flow *schema.SupplyFlow) error {
+
+	s.mu.RLock()
+	day, err := s.mergeSupplyFlow(flow)
+	s.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(day.Time.UnixNano()))
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketFlows), key, day)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.putSupplyFlow(day)
+	s.mu.Unlock()
+
+	return nil
+
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// getJSON unmarshals value stored by key, missing keys are ignored
+func getJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+
+	data := b.Get(key)
+	if data == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, v)
+
+}
+
+// putJSON marshals value and stores it by key
+func putJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, data)
+
+}
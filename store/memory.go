@@ -0,0 +1,291 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+)
+
+// MemoryStore keeps metrics in memory, they are lost on restart
+type MemoryStore struct {
+	snapshot       atomic.Value
+	mu             sync.RWMutex
+	stakingHistory map[string][]*schema.StakingRecordVersion
+	supplyHistory  []*schema.SupplyPoint
+	rewards        []*schema.Reward
+	stakerRewards  map[string][]*schema.Reward
+	rewardKeys     map[string]bool
+	supplyFlows    []*schema.SupplyFlow
+}
+
+// NewMemoryStore constructs empty in-memory store
+func NewMemoryStore() *MemoryStore {
+
+	return &MemoryStore{
+		stakingHistory: make(map[string][]*schema.StakingRecordVersion),
+		stakerRewards:  make(map[string][]*schema.Reward),
+		rewardKeys:     make(map[string]bool),
+	}
+
+}
+
+func (s *MemoryStore) Snapshot() *schema.Snapshot {
+	snapshot, _ := s.snapshot.Load().(*schema.Snapshot)
+	return snapshot
+}
+
+func (s *MemoryStore) SaveSnapshot(snapshot *schema.Snapshot) error {
+	s.snapshot.Store(snapshot)
+	return nil
+}
+
+func (s *MemoryStore) StakingHistory(identity string) []*schema.StakingRecordVersion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stakingHistory[strings.ToLower(identity)]
+}
+
+func (s *MemoryStore) AppendStakingHistory(versions ...*schema.StakingRecordVersion) (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+
+	for _, version := range versions {
+
+		key := strings.ToLower(version.Identity)
+		history := s.stakingHistory[key]
+
+		var prev *schema.StakingRecordVersion
+		duplicate := false
+		for _, v := range history {
+			if v.ChainIndex == version.ChainIndex {
+				duplicate = true
+				break
+			}
+			if v.ChainIndex < version.ChainIndex {
+				prev = v
+			}
+		}
+
+		if duplicate {
+			continue
+		}
+
+		version.Changes = schema.DiffStakingRecords(prev, version)
+
+		// copy on write, so readers keep a consistent slice
+		updated := make([]*schema.StakingRecordVersion, len(history), len(history)+1)
+		copy(updated, history)
+		s.stakingHistory[key] = append(updated, version)
+		added++
+
+	}
+
+	return added, nil
+
+}
+
+func (s *MemoryStore) SupplyHistory(from, to time.Time) []*schema.SupplyPoint {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.supplyHistory), func(i int) bool { return !s.supplyHistory[i].Time.Before(from) })
+	end := sort.Search(len(s.supplyHistory), func(i int) bool { return s.supplyHistory[i].Time.After(to) })
+
+	if start >= end {
+		return nil
+	}
+
+	return s.supplyHistory[start:end]
+
+}
+
+func (s *MemoryStore) AppendSupplyPoint(point *schema.SupplyPoint) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n := len(s.supplyHistory); n > 0 && !point.Time.After(s.supplyHistory[n-1].Time) {
+		return fmt.Errorf("supply point at %s is not newer than the last one at %s", point.Time, s.supplyHistory[n-1].Time)
+	}
+
+	// copy on write, so readers keep a consistent slice
+	updated := make([]*schema.SupplyPoint, len(s.supplyHistory), len(s.supplyHistory)+1)
+	copy(updated, s.supplyHistory)
+	s.supplyHistory = append(updated, point)
+
+	return nil
+
+}
+
+func (s *MemoryStore) Rewards(from, to time.Time) []*schema.Reward {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.rewards), func(i int) bool { return !s.rewards[i].FirstSeen.Before(from) })
+	end := sort.Search(len(s.rewards), func(i int) bool { return s.rewards[i].FirstSeen.After(to) })
+
+	if start >= end {
+		return nil
+	}
+
+	return s.rewards[start:end]
+
+}
+
+func (s *MemoryStore) StakerRewards(identity string) []*schema.Reward {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stakerRewards[strings.ToLower(identity)]
+}
+
+func (s *MemoryStore) AppendRewards(rewards ...*schema.Reward) (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rewards = s.newRewards(rewards)
+	if len(rewards) == 0 {
+		return 0, nil
+	}
+
+	// copy on write, so readers keep a consistent slice
+	s.rewards = appendRewards(s.rewards, rewards...)
+
+	for _, reward := range rewards {
+		key := strings.ToLower(reward.Identity)
+		s.stakerRewards[key] = appendRewards(s.stakerRewards[key], reward)
+		s.rewardKeys[rewardKey(reward)] = true
+	}
+
+	return len(rewards), nil
+
+}
+
+// newRewards returns rewards that are not stored yet, the caller must hold the lock
+func (s *MemoryStore) newRewards(rewards []*schema.Reward) []*schema.Reward {
+
+	res := []*schema.Reward{}
+	seen := make(map[string]bool)
+
+	for _, reward := range rewards {
+		key := rewardKey(reward)
+		if s.rewardKeys[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		res = append(res, reward)
+	}
+
+	return res
+
+}
+
+// appendRewards returns a new slice of rewards and added rewards ordered by time
+func appendRewards(rewards []*schema.Reward, added ...*schema.Reward) []*schema.Reward {
+
+	res := make([]*schema.Reward, len(rewards), len(rewards)+len(added))
+	copy(res, rewards)
+	res = append(res, added...)
+
+	// concurrent workers may append rewards slightly out of order
+	sort.SliceStable(res, func(i, j int) bool { return res[i].FirstSeen.Before(res[j].FirstSeen) })
+
+	return res
+
+}
+
+// rewardKey identifies reward by rewards account and transaction
+func rewardKey(reward *schema.Reward) string {
+	return strings.ToLower(reward.Account) + "@" + reward.TxID
+}
+
+func (s *MemoryStore) SupplyFlows(from, to time.Time) []*schema.SupplyFlow {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from = from.UTC().Truncate(24 * time.Hour)
+
+	start := sort.Search(len(s.supplyFlows), func(i int) bool { return !s.supplyFlows[i].Time.Before(from) })
+	end := sort.Search(len(s.supplyFlows), func(i int) bool { return s.supplyFlows[i].Time.After(to) })
+
+	if start >= end {
+		return nil
+	}
+
+	return s.supplyFlows[start:end]
+
+}
+
+func (s *MemoryStore) AddSupplyFlow(flow *schema.SupplyFlow) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day, err := s.mergeSupplyFlow(flow)
+	if err != nil {
+		return err
+	}
+
+	s.putSupplyFlow(day)
+
+	return nil
+
+}
+
+// mergeSupplyFlow returns a new flow of the UTC day of flow with flow added, the caller must hold the lock
+func (s *MemoryStore) mergeSupplyFlow(flow *schema.SupplyFlow) (*schema.SupplyFlow, error) {
+
+	day := &schema.SupplyFlow{Time: flow.Time.UTC().Truncate(24 * time.Hour)}
+
+	if n := len(s.supplyFlows); n > 0 {
+		last := s.supplyFlows[n-1]
+		if day.Time.Before(last.Time) {
+			return nil, fmt.Errorf("supply flow at %s is older than the last day %s", flow.Time, last.Time)
+		}
+		if day.Time.Equal(last.Time) {
+			*day = *last
+		}
+	}
+
+	day.Issued = day.Issued.Add(flow.Issued)
+	day.Burned = day.Burned.Add(flow.Burned)
+	day.NetChange = day.NetChange.Add(flow.NetChange)
+	day.Burns += flow.Burns
+
+	return day, nil
+
+}
+
+// putSupplyFlow replaces the last day with day of the same time or appends it, the caller must hold the lock
+func (s *MemoryStore) putSupplyFlow(day *schema.SupplyFlow) {
+
+	n := len(s.supplyFlows)
+
+	// copy on write, so readers keep a consistent slice
+	updated := make([]*schema.SupplyFlow, n, n+1)
+	copy(updated, s.supplyFlows)
+
+	if n > 0 && updated[n-1].Time.Equal(day.Time) {
+		updated[n-1] = day
+	} else {
+		updated = append(updated, day)
+	}
+
+	s.supplyFlows = updated
+
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -1,11 +1,61 @@
 package store
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/AccumulateNetwork/metrics-api/config"
 	"github.com/AccumulateNetwork/metrics-api/schema"
 )
 
-var StakingRecords *schema.StakingRecords
-var ACME *schema.ACME
-var UpdatedAt *time.Time
+const DriverMemory = "memory"
+const DriverBolt = "bolt"
+
+// Store keeps ingested metrics
+type Store interface {
+	// Snapshot returns the latest saved snapshot or nil if there is none yet.
+	// Returned snapshot is shared between readers and must not be modified.
+	Snapshot() *schema.Snapshot
+	// SaveSnapshot atomically replaces the latest snapshot
+	SaveSnapshot(snapshot *schema.Snapshot) error
+
+	// StakingHistory returns versions of staking record by Identity (case insensitive)
+	StakingHistory(identity string) []*schema.StakingRecordVersion
+	// AppendStakingHistory adds new versions of staking records and returns the number of added versions.
+	// Versions are deduplicated by chain index, so a rescan does not duplicate the history.
+	AppendStakingHistory(versions ...*schema.StakingRecordVersion) (int, error)
+
+	// SupplyHistory returns supply points recorded within [from, to], ordered by time
+	SupplyHistory(from, to time.Time) []*schema.SupplyPoint
+	// AppendSupplyPoint records supply point, points must be appended in time order
+	AppendSupplyPoint(point *schema.SupplyPoint) error
+
+	// Rewards returns staking rewards first seen within [from, to], ordered by time
+	Rewards(from, to time.Time) []*schema.Reward
+	// StakerRewards returns staking rewards of staker by Identity (case insensitive), ordered by time
+	StakerRewards(identity string) []*schema.Reward
+	// AppendRewards records new rewards and returns the number of added rewards.
+	// Rewards are deduplicated by account and transaction ID, so rescanning an account does not duplicate them.
+	AppendRewards(rewards ...*schema.Reward) (int, error)
+
+	// SupplyFlows returns daily ACME supply flows of days within [from, to], ordered by time
+	SupplyFlows(from, to time.Time) []*schema.SupplyFlow
+	// AddSupplyFlow adds flow to the flow of its UTC day, flows must be added in time order
+	AddSupplyFlow(flow *schema.SupplyFlow) error
+
+	Close() error
+}
+
+// NewStore constructs the store selected by config
+func NewStore(cfg *config.Store) (Store, error) {
+
+	switch cfg.Driver {
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	case DriverBolt:
+		return NewBoltStore(cfg.Path)
+	}
+
+	return nil, fmt.Errorf("unknown store driver '%s'", cfg.Driver)
+
+}
@@ -0,0 +1,204 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/metrics-api/schema"
+	"github.com/AccumulateNetwork/metrics-api/store"
+)
+
+var day = time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+func amount(t *testing.T, s string) schema.Amount {
+
+	t.Helper()
+
+	a, err := schema.ParseAmount(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+
+}
+
+// stores runs test against memory and bolt stores
+func stores(t *testing.T, test func(t *testing.T, st store.Store)) {
+
+	t.Run("memory", func(t *testing.T) {
+		test(t, store.NewMemoryStore())
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		st, err := store.NewBoltStore(filepath.Join(t.TempDir(), "metrics.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		test(t, st)
+	})
+
+}
+
+func TestBoltReopen(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "metrics.db")
+
+	st, err := store.NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor := int64(42)
+	err = st.SaveSnapshot(&schema.Snapshot{
+		ID:             7,
+		ACME:           &schema.ACME{Symbol: "ACME", Precision: 8, Total: amount(t, "30000000000000000"), Max: amount(t, "50000000000000000")},
+		StakingRecords: []*schema.StakingRecord{{Type: "pure", Identity: "acc://pure.acme", Stake: "acc://pure.acme/staking", Rewards: "acc://pure.acme/rewards", Balance: amount(t, "2000000000000")}},
+		StakingCursor:  4,
+		RewardsCursors: map[string]int64{"acc://pure.acme/rewards": 3},
+		BurnsCursor:    &cursor,
+		UpdatedAt:      day,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = st.AppendStakingHistory(
+		&schema.StakingRecordVersion{Type: "delegated", Identity: "acc://delegator.acme", Delegate: "acc://validator.acme", ChainIndex: 1},
+		&schema.StakingRecordVersion{Type: "delegated", Identity: "acc://delegator.acme", Delegate: "acc://other.acme", ChainIndex: 5},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = st.AppendSupplyPoint(&schema.SupplyPoint{Time: day, SnapshotID: 7, Total: amount(t, "300"), Staked: amount(t, "100"), Locked: amount(t, "50"), Circulating: amount(t, "150")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = st.AppendRewards(&schema.Reward{Identity: "acc://pure.acme", Account: "acc://pure.acme/rewards", TxID: "acc://01@staking.acme/payout", Amount: amount(t, "25000000000"), FirstSeen: day, Backfill: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flow := range []*schema.SupplyFlow{
+		{Time: day.Add(time.Hour), Issued: amount(t, "10"), Burned: amount(t, "3"), NetChange: amount(t, "7"), Burns: 1},
+		{Time: day.Add(2 * time.Hour), Issued: amount(t, "5"), NetChange: amount(t, "5")},
+	} {
+		if err = st.AddSupplyFlow(flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err = store.NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	snapshot := st.Snapshot()
+	if snapshot == nil || snapshot.ID != 7 || snapshot.StakingCursor != 4 || !snapshot.UpdatedAt.Equal(day) {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	if snapshot.ACME.Total.String() != "30000000000000000" || snapshot.ACME.Max.String() != "50000000000000000" {
+		t.Errorf("unexpected ACME supply %+v", snapshot.ACME)
+	}
+	if len(snapshot.StakingRecords) != 1 || snapshot.StakingRecords[0].Balance.String() != "2000000000000" {
+		t.Errorf("unexpected staking records %+v", snapshot.StakingRecords)
+	}
+	if snapshot.RewardsCursors["acc://pure.acme/rewards"] != 3 {
+		t.Errorf("unexpected rewards cursors %v", snapshot.RewardsCursors)
+	}
+	if snapshot.BurnsCursor == nil || *snapshot.BurnsCursor != 42 {
+		t.Errorf("expected burns cursor 42, got %v", snapshot.BurnsCursor)
+	}
+
+	history := st.StakingHistory("ACC://Delegator.acme")
+	if len(history) != 2 || history[1].ChainIndex != 5 || len(history[1].Changes) != 1 || history[1].Changes[0] != "delegate" {
+		t.Errorf("unexpected staking history %+v", history)
+	}
+
+	points := st.SupplyHistory(day, day)
+	if len(points) != 1 || points[0].SnapshotID != 7 || points[0].Total.String() != "300" || points[0].Locked.String() != "50" || points[0].Circulating.String() != "150" {
+		t.Errorf("unexpected supply history %+v", points)
+	}
+
+	rewards := st.StakerRewards("acc://PURE.acme")
+	if len(rewards) != 1 || rewards[0].Amount.String() != "25000000000" || !rewards[0].Backfill || !rewards[0].FirstSeen.Equal(day) {
+		t.Errorf("unexpected rewards %+v", rewards)
+	}
+	if n, err := st.AppendRewards(rewards[0]); err != nil || n != 0 {
+		t.Errorf("expected reloaded reward to be deduplicated, added %d: %v", n, err)
+	}
+
+	flows := st.SupplyFlows(day, day)
+	if len(flows) != 1 || flows[0].Issued.String() != "15" || flows[0].Burned.String() != "3" || flows[0].NetChange.String() != "12" || flows[0].Burns != 1 {
+		t.Errorf("expected flows merged into a single day, got %+v", flows)
+	}
+
+}
+
+func TestStakingHistoryDeduplication(t *testing.T) {
+
+	stores(t, func(t *testing.T, st store.Store) {
+
+		versions := []*schema.StakingRecordVersion{
+			{Type: "pure", Identity: "acc://staker.acme", ChainIndex: 2},
+			{Type: "coreValidator", Identity: "acc://staker.acme", ChainIndex: 6},
+		}
+
+		if n, err := st.AppendStakingHistory(versions...); err != nil || n != 2 {
+			t.Fatalf("expected 2 added versions, got %d: %v", n, err)
+		}
+
+		// a rescan appends the same chain indexes again
+		n, err := st.AppendStakingHistory(
+			&schema.StakingRecordVersion{Type: "pure", Identity: "acc://staker.acme", ChainIndex: 2},
+			&schema.StakingRecordVersion{Type: "coreValidator", Identity: "acc://staker.acme", ChainIndex: 6},
+		)
+		if err != nil || n != 0 {
+			t.Errorf("expected no added versions, got %d: %v", n, err)
+		}
+
+		history := st.StakingHistory("acc://staker.acme")
+		if len(history) != 2 || history[1].Changes[0] != "type" {
+			t.Errorf("unexpected history %+v", history)
+		}
+
+	})
+
+}
+
+func TestOutOfOrder(t *testing.T) {
+
+	stores(t, func(t *testing.T, st store.Store) {
+
+		if err := st.AppendSupplyPoint(&schema.SupplyPoint{Time: day.Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.AppendSupplyPoint(&schema.SupplyPoint{Time: day.Add(time.Hour)}); err == nil {
+			t.Error("expected error for supply point of the same time")
+		}
+		if err := st.AppendSupplyPoint(&schema.SupplyPoint{Time: day}); err == nil {
+			t.Error("expected error for older supply point")
+		}
+		if points := st.SupplyHistory(day, day.Add(time.Hour)); len(points) != 1 {
+			t.Errorf("expected 1 supply point, got %d", len(points))
+		}
+
+		if err := st.AddSupplyFlow(&schema.SupplyFlow{Time: day.Add(24 * time.Hour), Issued: amount(t, "1")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.AddSupplyFlow(&schema.SupplyFlow{Time: day.Add(23 * time.Hour), Issued: amount(t, "1")}); err == nil {
+			t.Error("expected error for flow of an older day")
+		}
+		if flows := st.SupplyFlows(day, day.Add(24*time.Hour)); len(flows) != 1 || flows[0].Issued.String() != "1" {
+			t.Errorf("expected a single flow of the later day, got %+v", flows)
+		}
+
+	})
+
+}
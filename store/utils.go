@@ -1,15 +1,18 @@
 package store
 
 import (
+	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/AccumulateNetwork/metrics-api/schema"
 )
 
 // SearchStakingRecordByIdentity searches staking record by Identity (case insensitive)
-func SearchStakingRecordByIdentity(identity string) *schema.StakingRecord {
+func SearchStakingRecordByIdentity(records []*schema.StakingRecord, identity string) *schema.StakingRecord {
 
-	for _, r := range StakingRecords.Items {
+	for _, r := range records {
 		if strings.EqualFold(r.Identity, identity) {
 			return r
 		}
@@ -20,24 +23,91 @@ func SearchStakingRecordByIdentity(identity string) *schema.StakingRecord {
 }
 
 // GetTotalStake returns total staked ACME
-func GetTotalStake() int64 {
+func GetTotalStake(records []*schema.StakingRecord) schema.Amount {
 
-	total := int64(0)
+	total := schema.Amount{}
 
-	for _, r := range StakingRecords.Items {
-		total += r.Balance
+	for _, r := range records {
+		total = total.Add(r.Balance)
 	}
 
 	return total
 
 }
 
+// GetLockedSupply returns total balance of locked accounts, staking accounts are skipped as their balance is staked
+func GetLockedSupply(accounts []*schema.LockedAccount) schema.Amount {
+
+	total := schema.Amount{}
+
+	for _, a := range accounts {
+		if !a.Staked {
+			total = total.Add(a.Balance)
+		}
+	}
+
+	return total
+
+}
+
+// GetCirculatingSupply returns ACME supply that is neither staked nor locked
+func GetCirculatingSupply(snapshot *schema.Snapshot) schema.Amount {
+
+	if snapshot.ACME == nil {
+		return schema.Amount{}
+	}
+
+	return snapshot.ACME.Total.Sub(GetTotalStake(snapshot.StakingRecords)).Sub(GetLockedSupply(snapshot.LockedAccounts))
+
+}
+
+// GetStakerRank returns position of record by balance among records, starting from 1.
+// Records with equal balance share the same rank.
+func GetStakerRank(records []*schema.StakingRecord, record *schema.StakingRecord) int {
+
+	rank := 1
+
+	for _, r := range records {
+		if r.Balance.Cmp(record.Balance) > 0 {
+			rank++
+		}
+	}
+
+	return rank
+
+}
+
+// GetStakingType returns staking type of record, unknown types are counted as "pure"
+func GetStakingType(record *schema.StakingRecord) string {
+
+	switch record.Type {
+	case "coreValidator", "coreFollower", "stakingValidator", "delegated":
+		return record.Type
+	}
+
+	return "pure"
+
+}
+
+// GetStakeByType returns total staked ACME by staking type
+func GetStakeByType(records []*schema.StakingRecord) map[string]schema.Amount {
+
+	res := make(map[string]schema.Amount)
+
+	for _, r := range records {
+		res[GetStakingType(r)] = res[GetStakingType(r)].Add(r.Balance)
+	}
+
+	return res
+
+}
+
 // GetValidatorsNumber returns number of validators
-func GetValidatorsNumber() *schema.ValidatorsNumber {
+func GetValidatorsNumber(records []*schema.StakingRecord) *schema.ValidatorsNumber {
 
 	res := &schema.ValidatorsNumber{}
 
-	for _, r := range StakingRecords.Items {
+	for _, r := range records {
 		switch r.Type {
 		case "coreValidator":
 			res.CoreValidator++
@@ -55,3 +125,261 @@ func GetValidatorsNumber() *schema.ValidatorsNumber {
 	return res
 
 }
+
+// DownsampleSupply groups supply points by interval in UTC and aggregates every group into a candle.
+// Intervals are aligned to the zero time, which makes days start at midnight and weeks on Monday.
+func DownsampleSupply(points []*schema.SupplyPoint, interval time.Duration) []*schema.SupplyCandle {
+
+	res := []*schema.SupplyCandle{}
+
+	var candle *schema.SupplyCandle
+
+	for _, p := range points {
+
+		t := p.Time.UTC().Truncate(interval)
+
+		if candle == nil || !candle.Time.Equal(t) {
+			candle = &schema.SupplyCandle{
+				Time:        t,
+				Total:       &schema.OHLC{Open: p.Total, Min: p.Total, Max: p.Total},
+				Max:         &schema.OHLC{Open: p.Max, Min: p.Max, Max: p.Max},
+				Staked:      &schema.OHLC{Open: p.Staked, Min: p.Staked, Max: p.Staked},
+				Locked:      &schema.OHLC{Open: p.Locked, Min: p.Locked, Max: p.Locked},
+				Circulating: &schema.OHLC{Open: p.Circulating, Min: p.Circulating, Max: p.Circulating},
+			}
+			res = append(res, candle)
+		}
+
+		candle.Points++
+		candle.Total.Add(p.Total)
+		candle.Max.Add(p.Max)
+		candle.Staked.Add(p.Staked)
+		candle.Locked.Add(p.Locked)
+		candle.Circulating.Add(p.Circulating)
+
+	}
+
+	return res
+
+}
+
+// GetTotalRewards sums amounts of rewards
+func GetTotalRewards(rewards []*schema.Reward) schema.Amount {
+
+	total := schema.Amount{}
+
+	for _, r := range rewards {
+		total = total.Add(r.Amount)
+	}
+
+	return total
+
+}
+
+// GroupRewards sums rewards ordered by time into periods of interval, aligned like DownsampleSupply.
+// Periods without payouts are omitted. Backfilled payouts are skipped, as they were paid at unknown time.
+func GroupRewards(rewards []*schema.Reward, interval time.Duration) []*schema.RewardsPeriod {
+
+	res := []*schema.RewardsPeriod{}
+
+	var period *schema.RewardsPeriod
+
+	for _, r := range rewards {
+
+		if r.Backfill {
+			continue
+		}
+
+		t := r.FirstSeen.UTC().Truncate(interval)
+
+		if period == nil || !period.Time.Equal(t) {
+			period = &schema.RewardsPeriod{Time: t}
+			res = append(res, period)
+		}
+
+		period.Payouts++
+		period.Amount = period.Amount.Add(r.Amount)
+
+	}
+
+	return res
+
+}
+
+// GroupSupplyFlows sums daily flows ordered by time into periods of interval, aligned like DownsampleSupply
+func GroupSupplyFlows(flows []*schema.SupplyFlow, interval time.Duration) []*schema.SupplyFlow {
+
+	res := []*schema.SupplyFlow{}
+
+	var period *schema.SupplyFlow
+
+	for _, f := range flows {
+
+		t := f.Time.UTC().Truncate(interval)
+
+		if period == nil || !period.Time.Equal(t) {
+			period = &schema.SupplyFlow{Time: t}
+			res = append(res, period)
+		}
+
+		period.Issued = period.Issued.Add(f.Issued)
+		period.Burned = period.Burned.Add(f.Burned)
+		period.NetChange = period.NetChange.Add(f.NetChange)
+		period.Burns += f.Burns
+
+	}
+
+	return res
+
+}
+
+// YieldCompoundingPeriods is the number of payouts per year assumed by APY, staking rewards are paid weekly
+const YieldCompoundingPeriods = 52
+
+// GetYield returns realised yield of stake from rewards first seen within the trailing window of days before now.
+// Payouts are tracked since the given time, so a window that starts before it is not covered and nil is returned,
+// as annualising a partial window overstates yield. Backfilled payouts are skipped.
+// Nil is also returned if yield is not finite, e.g. for a dust stake.
+func GetYield(rewards []*schema.Reward, stake schema.Amount, days int, since, now time.Time) *schema.Yield {
+
+	from := now.Add(-time.Duration(days) * 24 * time.Hour)
+	if from.Before(since) {
+		return nil
+	}
+
+	res := &schema.Yield{Window: days, Days: float64(days), Stake: stake}
+
+	for _, r := range rewards {
+		if !r.Backfill && r.FirstSeen.After(from) && !r.FirstSeen.After(now) {
+			res.Rewards = res.Rewards.Add(r.Amount)
+		}
+	}
+
+	if stake.Sign() > 0 {
+		res.APR = res.Rewards.Ratio(stake) * 365 / res.Days
+		res.APY = math.Pow(1+res.APR/YieldCompoundingPeriods, YieldCompoundingPeriods) - 1
+	}
+
+	if math.IsInf(res.APR, 0) || math.IsNaN(res.APR) || math.IsInf(res.APY, 0) || math.IsNaN(res.APY) {
+		return nil
+	}
+
+	return res
+
+}
+
+// GetValidators returns core and staking validators with stake delegated to them, ordered by total stake
+func GetValidators(records []*schema.StakingRecord) []*schema.Validator {
+
+	res := []*schema.Validator{}
+	validators := make(map[string]*schema.Validator)
+
+	for _, r := range records {
+		if r.Type != "coreValidator" && r.Type != "stakingValidator" {
+			continue
+		}
+		v := &schema.Validator{
+			Identity:           r.Identity,
+			Type:               r.Type,
+			Status:             r.Status,
+			AcceptingDelegates: r.AcceptingDelegates,
+			Balance:            r.Balance,
+			TotalStake:         r.Balance,
+		}
+		validators[TrimScheme(r.Identity)] = v
+		res = append(res, v)
+	}
+
+	for _, r := range records {
+		if r.Type != "delegated" {
+			continue
+		}
+		if v, ok := validators[TrimScheme(r.Delegate)]; ok {
+			v.Delegated = v.Delegated.Add(r.Balance)
+			v.Delegators++
+			v.TotalStake = v.TotalStake.Add(r.Balance)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if c := res[i].TotalStake.Cmp(res[j].TotalStake); c != 0 {
+			return c > 0
+		}
+		return strings.ToLower(res[i].Identity) < strings.ToLower(res[j].Identity)
+	})
+
+	return res
+
+}
+
+// StakingFilter selects staking records, empty fields match any record
+type StakingFilter struct {
+	Type               string
+	Status             string
+	Delegate           string
+	AcceptingDelegates string
+	MinBalance         *schema.Amount
+	MaxBalance         *schema.Amount
+	// IdentityPrefix matches identities starting with it, with or without acc://
+	IdentityPrefix string
+}
+
+// FilterStakingRecords returns records matching filter (case insensitive), records are not copied
+func FilterStakingRecords(records []*schema.StakingRecord, filter *StakingFilter) []*schema.StakingRecord {
+
+	res := []*schema.StakingRecord{}
+
+	prefix := TrimScheme(filter.IdentityPrefix)
+
+	for _, r := range records {
+		switch {
+		case filter.Type != "" && !strings.EqualFold(GetStakingType(r), filter.Type):
+		case filter.Status != "" && !strings.EqualFold(r.Status, filter.Status):
+		case filter.Delegate != "" && !strings.EqualFold(TrimScheme(r.Delegate), TrimScheme(filter.Delegate)):
+		case filter.AcceptingDelegates != "" && !strings.EqualFold(r.AcceptingDelegates, filter.AcceptingDelegates):
+		case filter.MinBalance != nil && r.Balance.Cmp(*filter.MinBalance) < 0:
+		case filter.MaxBalance != nil && r.Balance.Cmp(*filter.MaxBalance) > 0:
+		case prefix != "" && !strings.HasPrefix(TrimScheme(r.Identity), prefix):
+		default:
+			res = append(res, r)
+		}
+	}
+
+	return res
+
+}
+
+// SortStakingRecords sorts records in place by balance, identity or type, ties are ordered by identity
+func SortStakingRecords(records []*schema.StakingRecord, field string, desc bool) {
+
+	less := func(a, b *schema.StakingRecord) bool {
+		switch field {
+		case "balance":
+			if c := a.Balance.Cmp(b.Balance); c != 0 {
+				return c < 0
+			}
+		case "type":
+			if ta, tb := GetStakingType(a), GetStakingType(b); ta != tb {
+				return ta < tb
+			}
+		}
+		return strings.ToLower(a.Identity) < strings.ToLower(b.Identity)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if desc {
+			return less(records[j], records[i])
+		}
+		return less(records[i], records[j])
+	})
+
+}
+
+// TrimScheme lowercases URL and removes acc:// scheme, use it to compare account URLs
+func TrimScheme(url string) string {
+
+	url = strings.ToLower(url)
+
+	return strings.TrimPrefix(url, "acc://")
+
+}